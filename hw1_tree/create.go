@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"hw1_tree/tree"
+)
+
+// runCreate builds a directory skeleton from a tree printed with -f.
+func runCreate(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" create", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go create <tree.txt|-> <dir>")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			reportErrors(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	m, err := tree.ParseText(in)
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	if err := tree.WriteFS(args[1], m); err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"hw1_tree/tree"
+)
+
+// runDiff compares two trees. Like diff(1), it exits with 0 when they
+// match, 1 when they differ and 2 on errors.
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" diff", flag.ExitOnError)
+	wf := addWalkFlags(flags)
+	hideUnchanged := flags.Bool("hide-unchanged", false, "don't print subtrees without changes")
+	ignoreTime := flags.Bool("ignore-mtime", false, "don't compare modification times")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go diff [flags] <old> <new>")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := wf.options()
+	opts.PrintFiles = true
+	var trees [2]*tree.Entry
+	for i, root := range args {
+		src, err := openSource(root)
+		if err != nil {
+			reportErrors(os.Stderr, err)
+			return 2
+		}
+		trees[i], err = src.collect(context.Background(), opts)
+		src.Close()
+		if err != nil {
+			reportErrors(os.Stderr, err)
+			return 2
+		}
+	}
+
+	diffOpts := tree.DiffOptions{
+		IgnoreTime:    *ignoreTime,
+		HideUnchanged: *hideUnchanged,
+		Units:         wf.units(),
+	}
+	d := tree.Diff(trees[0], trees[1], diffOpts)
+	if err := tree.WriteDiff(os.Stdout, d, diffOpts); err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+	if d.HasChanges() {
+		return 1
+	}
+	return 0
+}
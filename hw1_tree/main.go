@@ -1,85 +1,464 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"os"
-	"sort"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"hw1_tree/tree"
 )
 
 func main() {
-	out := os.Stdout
-	if !(len(os.Args) == 2 || len(os.Args) == 3) {
-		fmt.Println("usage go run main.go . [-f]")
-        return
-	}
-	path := os.Args[1]
-	printFiles := len(os.Args) == 3 && os.Args[2] == "-f"
-	err := dirTree(out, path, printFiles)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "snapshot":
+			os.Exit(runSnapshot(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		case "create":
+			os.Exit(runCreate(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		}
+	}
+	os.Exit(runTree(os.Args[1:]))
+}
+
+// walkFlags are the flags shared by all commands that walk a tree.
+type walkFlags struct {
+	printFiles  *bool
+	keepGoing   *bool
+	maxDepth    *int
+	maxEntries  *int
+	include     stringList
+	exclude     stringList
+	gitIgnore   *bool
+	prune       *bool
+	symlinks    *bool
+	follow      *bool
+	workers     *int
+	sortBy      *string
+	dirsFirst   *bool
+	reverse     *bool
+	human       *bool
+	si          *bool
+	hash        *string
+	hidden      *bool
+	lines       *bool
+	mime        *bool
+	includeMIME stringList
+	excludeMIME stringList
+
+	minSize        int64
+	maxSize        int64
+	modifiedAfter  time.Time
+	modifiedBefore time.Time
+	types          []tree.FileClass
+}
+
+func addWalkFlags(flags *flag.FlagSet) *walkFlags {
+	f := &walkFlags{}
+	f.printFiles = flags.Bool("f", false, "print files")
+	f.keepGoing = flags.Bool("k", false, "keep going on unreadable directories")
+	f.maxDepth = flags.Int("L", 0, "max display depth, 0 for no limit")
+	f.maxEntries = flags.Int("n", 0, "max entries per directory, 0 for no limit")
+	flags.Var(&f.include, "include", "show only files matching the glob, repeatable")
+	flags.Var(&f.exclude, "exclude", "hide entries matching the glob, repeatable")
+	f.gitIgnore = flags.Bool("gitignore", false, "hide paths ignored by .gitignore files")
+	f.prune = flags.Bool("prune", false, "hide directories left empty by the filters")
+	f.symlinks = flags.Bool("symlinks", false, "print symlink targets")
+	f.follow = flags.Bool("l", false, "follow symlinks to directories")
+	f.workers = flags.Int("j", runtime.NumCPU(), "number of directories read at once")
+	f.sortBy = flags.String("sort", "name", "sort by name, natural, size, mtime or ext")
+	f.dirsFirst = flags.Bool("dirsfirst", false, "list directories before files")
+	f.reverse = flags.Bool("r", false, "reverse the sort order")
+	f.human = flags.Bool("h", false, "print sizes in KiB, MiB, ...")
+	f.si = flags.Bool("si", false, "print sizes in kB, MB, ...")
+	f.hash = flags.String("hash", "", "hash file contents with sha256 or crc64")
+	f.hidden = flags.Bool("hidden", true, "show entries whose name starts with a dot")
+	f.lines = flags.Bool("lines", false, "count lines of text files, with totals per directory and language")
+	f.mime = flags.Bool("mime", false, "print the media type sniffed from the content of files")
+	flags.Var(&f.includeMIME, "include-mime", "show only files of a media type such as image/png or image, repeatable")
+	flags.Var(&f.excludeMIME, "exclude-mime", "hide files of a media type, repeatable")
+	flags.Func("min-size", "show only files of at least this size, such as 10MiB", func(v string) (err error) {
+		f.minSize, err = tree.ParseSize(v)
+		return err
+	})
+	flags.Func("max-size", "show only files of at most this size", func(v string) (err error) {
+		f.maxSize, err = tree.ParseSize(v)
+		return err
+	})
+	flags.Func("modified-after", "show only files modified after a time, a date or a duration ago such as 24h", func(v string) (err error) {
+		f.modifiedAfter, err = parseTime(v)
+		return err
+	})
+	flags.Func("modified-before", "show only files modified before a time, a date or a duration ago", func(v string) (err error) {
+		f.modifiedBefore, err = parseTime(v)
+		return err
+	})
+	flags.Func("type", "show only file, dir, symlink, socket or exec entries, comma-separated and repeatable", func(v string) error {
+		for _, c := range strings.Split(v, ",") {
+			f.types = append(f.types, tree.FileClass(c))
+		}
+		return nil
+	})
+	return f
+}
+
+func (f *walkFlags) options() tree.Options {
+	return tree.Options{
+		PrintFiles: *f.printFiles,
+		KeepGoing:  *f.keepGoing,
+		MaxDepth:   *f.maxDepth,
+		MaxEntries: *f.maxEntries,
+		Include:    f.include,
+		Exclude:    f.exclude,
+		GitIgnore:  *f.gitIgnore,
+		HideHidden: !*f.hidden,
+		Prune:      *f.prune,
+
+		MinSize:        f.minSize,
+		MaxSize:        f.maxSize,
+		ModifiedAfter:  f.modifiedAfter,
+		ModifiedBefore: f.modifiedBefore,
+		Types:          f.types,
+
+		Symlinks:    *f.symlinks,
+		FollowLinks: *f.follow,
+		Workers:     *f.workers,
+
+		SortBy:    tree.SortKey(*f.sortBy),
+		DirsFirst: *f.dirsFirst,
+		Reverse:   *f.reverse,
+
+		Hash:       tree.HashKind(*f.hash),
+		CountLines: *f.lines,
+		SniffMIME:  *f.mime,
+
+		IncludeMIME: f.includeMIME,
+		ExcludeMIME: f.excludeMIME,
+	}
+}
+
+// parseTime accepts RFC 3339 times, dates and durations before now.
+func parseTime(v string) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("bad time %q, want 2006-01-02, 2006-01-02T15:04:05Z07:00 or a duration like 24h", v)
+}
+
+func (f *walkFlags) units() tree.Units {
+	switch {
+	case *f.si:
+		return tree.SI
+	case *f.human:
+		return tree.IEC
+	}
+	return tree.Bytes
+}
+
+func runTree(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	wf := addWalkFlags(flags)
+	dirSizes := flags.Bool("du", false, "print the total size of every directory")
+	summary := flags.Bool("summary", false, "print directory and file totals at the end")
+	showMode := flags.Bool("p", false, "print permissions")
+	showOwner := flags.Bool("u", false, "print owner names")
+	showGroup := flags.Bool("g", false, "print group names")
+	showTime := flags.Bool("D", false, "print modification times")
+	timeFormat := flags.String("timefmt", "Jan _2 15:04", "time layout for -D, as in Go's time.Format")
+	showInode := flags.Bool("inodes", false, "print inode numbers")
+	color := flags.String("color", "auto", "colorize output: always, never or auto, when stdout is a terminal")
+	charset := flags.String("charset", "unicode", "glyphs to draw the tree with: unicode or ascii")
+	indent := flags.Int("indent", 0, "spaces per level, 0 to indent with tabs")
+	format := flags.String("o", "text", "output format: text, json, yaml, html, dot or mermaid")
+	graphSizes := flags.Bool("sizes", false, "with -o dot or mermaid, label files with their sizes")
+	duplicates := flags.Bool("duplicates", false, "print groups of files with identical content instead of the tree")
+	watch := flags.Bool("watch", false, "keep redrawing the tree as it changes, until interrupted")
+	highlight := flags.Bool("highlight", false, "with -watch, highlight entries changed since the last redraw")
+	poll := flags.Duration("poll", 0, "with -watch, poll at this interval instead of using change notifications")
+	timeout := flags.Duration("timeout", 0, "stop reading directories after this long and print the partial tree, 0 for no limit")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go <dir|archive> [flags]")
+		fmt.Fprintln(flags.Output(), "       go run main.go diff [flags] <old> <new>")
+		fmt.Fprintln(flags.Output(), "       go run main.go snapshot [flags] <dir|archive> > manifest")
+		fmt.Fprintln(flags.Output(), "       go run main.go verify [flags] <manifest> <dir|archive>")
+		fmt.Fprintln(flags.Output(), "       go run main.go create <tree.txt|-> <dir>")
+		fmt.Fprintln(flags.Output(), "       go run main.go serve [flags] <dir>")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	p := tree.NewTextPrinter(os.Stdout)
+	p.Units = wf.units()
+	p.DirSizes = *dirSizes
+	p.ShowSummary = *summary
+	p.ShowLines = *wf.lines
+	p.ShowMIME = *wf.mime
+	p.ShowMode = *showMode
+	p.ShowOwner = *showOwner
+	p.ShowGroup = *showGroup
+	p.ShowTime = *showTime
+	p.TimeFormat = *timeFormat
+	p.ShowInode = *showInode
+	if p.Style, err = tree.NewStyle(*charset, *indent); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		return 2
+	}
+	switch *color {
+	case "always":
+		p.Colors = tree.ParseLSColors(os.Getenv("LS_COLORS"))
+	case "auto":
+		if isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" {
+			p.Colors = tree.ParseLSColors(os.Getenv("LS_COLORS"))
+		}
+	case "never":
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	src, err := openSource(args[0])
 	if err != nil {
-		panic(err.Error())
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	defer src.Close()
+	if *duplicates {
+		*format = "duplicates"
+	}
+	if *watch {
+		if src.fsys != nil || *format != "text" {
+			fmt.Fprintln(os.Stderr, "-watch needs a directory and text output")
+			return 2
+		}
+		wopts := tree.WatchOptions{Poll: *poll > 0, Interval: *poll}
+		if err := runWatch(os.Stdout, src.root, wf.options(), wopts, p, *highlight); err != nil {
+			reportErrors(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	graph := tree.GraphOptions{Sizes: *graphSizes, Units: p.Units}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
+	err = render(ctx, os.Stdout, src, *format, wf.options(), p, graph)
+	if err != nil && err == ctx.Err() && *format == "text" {
+		writeTruncated(os.Stdout, err)
+	}
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
-func dirTree(out io.Writer, currDir string, printFiles bool) error {
-	printDirTree("", out, currDir, printFiles)
+
+// runWatch redraws the tree of root on a cleared screen whenever it
+// changes, until the process is interrupted.
+func runWatch(out io.Writer, root string, opts tree.Options, wopts tree.WatchOptions, p *tree.TextPrinter, highlight bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Like Watch, redraws report directories vanishing mid-walk rather
+	// than stop.
+	opts.KeepGoing = true
+	return tree.Watch(ctx, root, opts, wopts, func(changed map[string]bool) error {
+		if highlight {
+			p.Highlight = changed
+		}
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+		err := tree.Walk(root, opts, p)
+		if _, ok := err.(tree.Errors); ok {
+			reportErrors(out, err)
+			return nil
+		}
+		return err
+	})
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
 	return nil
 }
 
-func printDirTree(prefix string, out io.Writer, currDir string, printFiles bool) {
-	f, _ := os.Open(currDir)
-	//	if err != nil {
-	//		fmt.Println("Could not open %s: %s", currDir, err.Error())
-	//	}
-	fileName := f.Name()
-	f.Close()
-	files, _ := ioutil.ReadDir(fileName)
-	//if err != nil {
-	//	fmt.Println("Could not read dir names in %s: %s", currDir, err.Error())
-	//}
-	filesMap := make(map[string]os.FileInfo)
-	var arrName []string
-	for _, file := range files {
-		if file.IsDir() || printFiles {
-			arrName = append(arrName, file.Name())
-			filesMap[file.Name()] = file
+// parseArgs allows flags both before and after the positional path,
+// so the old "main.go . -f" form keeps working.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
 		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// source is what the tree is printed for: a directory, or an archive
+// opened as a file system.
+type source struct {
+	name   string
+	root   string
+	fsys   fs.FS // nil for directories
+	closer io.Closer
+}
+
+func openSource(root string) (*source, error) {
+	if info, err := os.Stat(root); err != nil || info.IsDir() || !tree.IsArchive(root) {
+		return &source{name: root, root: root}, nil
+	}
+	fsys, closer, err := tree.OpenArchive(root)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(arrName)
-	var sortedFiles []os.FileInfo
-	for _, name := range arrName {
-		sortedFiles = append(sortedFiles, filesMap[name])
+	return &source{name: root, root: ".", fsys: fsys, closer: closer}, nil
+}
+
+func (s *source) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+func (s *source) walk(ctx context.Context, opts tree.Options, v tree.Visitor) error {
+	if s.fsys == nil {
+		return tree.WalkContext(ctx, s.root, opts, v)
 	}
-	files = sortedFiles
+	return tree.WalkFSContext(ctx, s.fsys, s.root, opts, v)
+}
 
-	length := len(files)
+func (s *source) collect(ctx context.Context, opts tree.Options) (*tree.Entry, error) {
+	if s.fsys == nil {
+		return tree.CollectContext(ctx, s.root, opts)
+	}
+	e, err := tree.CollectFSContext(ctx, s.fsys, s.root, opts)
+	if e != nil {
+		e.Name = s.name
+	}
+	return e, err
+}
 
-	for i, file := range files {
-		if file.IsDir() {
-			var nextPrefix string
-			if length > i+1 {
-				fmt.Fprintf(out, prefix+"├───"+"%s\n", file.Name())
-				nextPrefix = prefix + "│\t"
-			} else {
-				fmt.Fprintf(out, prefix+"└───"+"%s\n", file.Name())
-				nextPrefix = prefix + "\t"
+// render writes the tree of src in format. When ctx is done before the
+// whole tree is read, the partial tree is written and the error of ctx
+// returned.
+func render(ctx context.Context, out io.Writer, src *source, format string, opts tree.Options, text *tree.TextPrinter, graph tree.GraphOptions) error {
+	switch format {
+	case "text":
+		return src.walk(ctx, opts, text)
+	case "json", "yaml", "html", "dot", "mermaid", "duplicates":
+		if format == "duplicates" {
+			opts.PrintFiles = true
+			if opts.Hash == tree.NoHash {
+				opts.Hash = tree.SHA256
 			}
-			nextDir := currDir + "/" + file.Name()
-			printDirTree(nextPrefix, out, nextDir, printFiles)
-		} else if printFiles {
-			if file.Size() > 0 {
-				if length > i+1 {
-					fmt.Fprintf(out, prefix+"├───%s (%vb)\n", file.Name(), file.Size())
-				} else {
-					fmt.Fprintf(out, prefix+"└───%s (%vb)\n", file.Name(), file.Size())
-				}
-			} else {
-				if length > i+1 {
-					fmt.Fprintf(out, prefix+"├───%s (empty)\n", file.Name())
-				} else {
-					fmt.Fprintf(out, prefix+"└───%s (empty)\n", file.Name())
-				}
+		}
+		e, err := src.collect(ctx, opts)
+		if e == nil {
+			return err
+		}
+		write := tree.WriteJSON
+		switch format {
+		case "yaml":
+			write = tree.WriteYAML
+		case "html":
+			write = func(out io.Writer, e *tree.Entry) error {
+				return tree.WriteHTML(out, e, text.Units)
+			}
+		case "dot":
+			write = func(out io.Writer, e *tree.Entry) error {
+				return tree.WriteDOT(out, e, graph)
+			}
+		case "mermaid":
+			write = func(out io.Writer, e *tree.Entry) error {
+				return tree.WriteMermaid(out, e, graph)
+			}
+		case "duplicates":
+			write = func(out io.Writer, e *tree.Entry) error {
+				return tree.WriteDuplicates(out, e, text.Units)
 			}
 		}
+		if werr := write(out, e); werr != nil {
+			return werr
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func reportErrors(w io.Writer, err error) {
+	var errs tree.Errors
+	if !errors.As(err, &errs) {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintf(w, "%d errors:\n", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  %v\n", e)
 	}
 }
+
+func dirTree(out io.Writer, currDir string, printFiles bool) error {
+	return DirTreeContext(context.Background(), out, currDir, printFiles)
+}
+
+// DirTreeContext is dirTree that stops reading directories once ctx is
+// done, for example on a hung network mount. The part of the tree read
+// until then is written, followed by a truncation line, and the error of
+// ctx is returned.
+func DirTreeContext(ctx context.Context, out io.Writer, currDir string, printFiles bool) error {
+	err := tree.WalkContext(ctx, currDir, tree.Options{PrintFiles: printFiles}, tree.NewTextPrinter(out))
+	if err != nil && err == ctx.Err() {
+		writeTruncated(out, err)
+	}
+	return err
+}
+
+// writeTruncated ends a tree cut short by err.
+func writeTruncated(out io.Writer, err error) {
+	fmt.Fprintf(out, "… truncated: %v\n", err)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"hw1_tree/tree"
+)
+
+// runSnapshot writes the manifest of a tree to stdout.
+func runSnapshot(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" snapshot", flag.ExitOnError)
+	wf := addWalkFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go snapshot [flags] <dir|archive> > manifest")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := manifestOptions(wf.options())
+	if opts.Hash == tree.NoHash {
+		opts.Hash = tree.SHA256
+	}
+	src, err := openSource(args[0])
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	defer src.Close()
+	e, err := src.collect(context.Background(), opts)
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	if err := tree.WriteManifest(os.Stdout, e); err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// manifestOptions returns opts with what snapshot and verify record:
+// every file and the target of every symlink.
+func manifestOptions(opts tree.Options) tree.Options {
+	opts.PrintFiles = true
+	opts.Symlinks = true
+	return opts
+}
+
+// runVerify compares a tree with a manifest written by snapshot. It exits
+// with 0 when they match, 1 on drift and 2 on errors.
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" verify", flag.ExitOnError)
+	wf := addWalkFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go verify [flags] <manifest> <dir|archive>")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+	want, err := tree.ReadManifest(f)
+	f.Close()
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+
+	opts := manifestOptions(wf.options())
+	opts.Hash = tree.HashOf(want)
+	src, err := openSource(args[1])
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+	defer src.Close()
+	got, err := src.collect(context.Background(), opts)
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+
+	diffOpts := tree.DiffOptions{IgnoreTime: true, Units: wf.units()}
+	n, err := tree.WriteDeviations(os.Stdout, tree.Diff(want, got, diffOpts), diffOpts)
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 2
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d deviations from %s\n", args[1], n, args[0])
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hw1_tree/tree"
+)
+
+const testVerifyLinkResult = "changed  index.html (target ../good.ht -> ../evil.ht)\n"
+
+func TestVerifyLink(t *testing.T) {
+	// A link pointed elsewhere is drift, even with a target of the same
+	// length.
+	root := t.TempDir()
+	link := filepath.Join(root, "index.html")
+	if err := os.Symlink("../good.ht", link); err != nil {
+		t.Fatal(err)
+	}
+	opts := manifestOptions(tree.Options{Hash: tree.SHA256})
+	e, err := tree.Collect(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := new(bytes.Buffer)
+	if err := tree.WriteManifest(manifest, e); err != nil {
+		t.Fatal(err)
+	}
+	want, err := tree.ReadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../evil.ht", link); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tree.Collect(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffOpts := tree.DiffOptions{IgnoreTime: true}
+	out := new(bytes.Buffer)
+	n, err := tree.WriteDeviations(out, tree.Diff(want, got, diffOpts), diffOpts)
+	if err != nil || n != 1 || out.String() != testVerifyLinkResult {
+		t.Errorf("results not match, %d deviations, %v\nGot:\n%v\nExpected:\n%v", n, err, out.String(), testVerifyLinkResult)
+	}
+}
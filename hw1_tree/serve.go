@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"hw1_tree/tree"
+)
+
+// runServe serves the trees under a directory over HTTP.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" serve", flag.ExitOnError)
+	wf := addWalkFlags(flags)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage go run main.go serve [flags] <dir>")
+		fmt.Fprintln(flags.Output(), "The walk flags are the defaults of the /tree requests.")
+		flags.PrintDefaults()
+	}
+	args, err := parseArgs(flags, args)
+	if err != nil || len(args) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	s, err := newTreeServer(args[0], wf.options(), wf.units())
+	if err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	log.Printf("serving %s on http://%s/", args[0], *addr)
+	if err := http.ListenAndServe(*addr, s); err != nil {
+		reportErrors(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// treeServer answers GET /tree?path=…&depth=…&files=1&format=json|text
+// for the directories under root, and serves a small browser on /.
+type treeServer struct {
+	root  string // with symlinks resolved
+	fsys  fs.FS
+	opts  tree.Options
+	units tree.Units
+	mux   *http.ServeMux
+}
+
+func newTreeServer(root string, opts tree.Options, units tree.Units) (*treeServer, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, &tree.RootError{Path: root, Err: err}
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, &tree.RootError{Path: root, Err: tree.ErrNotDir}
+	}
+	// Requests are kept inside root by resolve, but followed links would
+	// lead the walk out of it.
+	opts.FollowLinks = false
+	// Once streaming has started, a failure can only be reported in the
+	// tree, as the error of its entry.
+	opts.KeepGoing = true
+	s := &treeServer{root: root, fsys: tree.DirFS(root), opts: opts, units: units, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/tree", s.serveTree)
+	s.mux.HandleFunc("/", s.serveBrowser)
+	return s, nil
+}
+
+func (s *treeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+var errOutsideRoot = errors.New("path is outside the root")
+
+// resolve turns the path of a request into a path of s.fsys. Paths are
+// always taken relative to the root, and symlinks leading out of it are
+// rejected.
+func (s *treeServer) resolve(p string) (string, error) {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return ".", nil
+	}
+	if !fs.ValidPath(p) {
+		return "", errOutsideRoot
+	}
+	real, err := filepath.EvalSymlinks(filepath.Join(s.root, filepath.FromSlash(p)))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errOutsideRoot
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (s *treeServer) serveTree(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	opts := s.opts
+	if v := q.Get("depth"); v != "" {
+		depth, err := strconv.Atoi(v)
+		if err != nil || depth < 0 {
+			http.Error(w, "bad depth", http.StatusBadRequest)
+			return
+		}
+		opts.MaxDepth = depth
+	}
+	if v := q.Get("files"); v != "" {
+		files, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "bad files", http.StatusBadRequest)
+			return
+		}
+		opts.PrintFiles = files
+	}
+
+	p, err := s.resolve(q.Get("path"))
+	var info fs.FileInfo
+	if err == nil {
+		if info, err = fs.Stat(s.fsys, p); err == nil && !info.IsDir() {
+			err = tree.ErrNotDir
+		}
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	var v tree.Visitor
+	switch q.Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		v = tree.NewJSONWriter(w, "/"+strings.TrimPrefix(p, "."), info)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		text := tree.NewTextPrinter(w)
+		text.Units = s.units
+		v = text
+	default:
+		http.Error(w, "unknown format", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	// The tree is written while it is visited, so once the root has been
+	// read failures can no longer be reported to the client. They are
+	// logged instead.
+	err = tree.WalkFSContext(r.Context(), s.fsys, p, opts, v)
+	var rootErr *tree.RootError
+	if errors.As(err, &rootErr) {
+		httpError(w, rootErr.Err)
+	} else if err != nil {
+		log.Printf("%s: %v", r.URL, err)
+	}
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errOutsideRoot):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case errors.Is(err, tree.ErrNotDir):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *treeServer) serveBrowser(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, browserPage)
+}
+
+// browserPage lists one directory at a time through the /tree API, the
+// path being kept in the fragment so that the back button works.
+const browserPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tree</title>
+<style>
+body { font-family: monospace; }
+ul { list-style: none; padding-left: 1.5em; }
+.size { color: #777; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1 id="path"></h1>
+<ul id="list"></ul>
+<script>
+function show() {
+  var dir = decodeURIComponent(location.hash.slice(1)) || "/";
+  document.getElementById("path").textContent = dir;
+  var list = document.getElementById("list");
+  fetch("tree?files=1&depth=1&path=" + encodeURIComponent(dir))
+    .then(function (r) { if (!r.ok) { throw new Error(r.status + " " + r.statusText); } return r.json(); })
+    .then(function (e) {
+      list.textContent = "";
+      if (dir !== "/") {
+        add("..", "#" + encodeURIComponent(dir.replace(/\/[^\/]*$/, "") || "/"));
+      }
+      (e.children || []).forEach(function (c) {
+        if (c.type === "dir") {
+          add(c.name + "/", "#" + encodeURIComponent(dir.replace(/\/$/, "") + "/" + c.name));
+        } else {
+          var li = add(c.target ? c.name + " -> " + c.target : c.name);
+          var size = li.appendChild(document.createElement("span"));
+          size.className = "size";
+          size.textContent = " (" + (c.size ? c.size + "b" : "empty") + ")";
+        }
+      });
+      if (e.omitted) {
+        add("… and " + e.omitted + " more");
+      }
+    })
+    .catch(function (err) {
+      list.textContent = "";
+      add(err.message).className = "error";
+    });
+  function add(text, href) {
+    var li = list.appendChild(document.createElement("li"));
+    var el = li;
+    if (href) {
+      el = li.appendChild(document.createElement("a"));
+      el.href = href;
+    }
+    el.textContent = text;
+    return li;
+  }
+}
+window.onhashchange = show;
+show();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"hw1_tree/tree"
+)
+
+func newTestServer(t *testing.T) *treeServer {
+	root, outside := t.TempDir(), t.TempDir()
+	for name, data := range map[string]string{
+		"file.txt":          "abc",
+		"sub/a.txt":         "a",
+		"sub/deep/b.txt":    "",
+		outside + "/secret": "secret",
+	} {
+		p := name
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, name)
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both temporary directories are in the same parent, so the link is
+	// the same for every run.
+	target, err := filepath.Rel(root, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newTreeServer(root, tree.Options{FollowLinks: true}, tree.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServeTree(t *testing.T) {
+	s := newTestServer(t)
+	for _, tc := range []struct {
+		method, url string
+		code        int
+		body        string
+	}{
+		{"GET", "/tree?format=text", 200, "└───sub\n\t└───deep\n"},
+		{"GET", "/tree?format=text&depth=1&files=1", 200, "├───escape (6b)\n├───file.txt (3b)\n└───sub\n"},
+		{"GET", "/tree?format=text&files=true&path=/sub/", 200, "├───a.txt (1b)\n└───deep\n\t└───b.txt (empty)\n"},
+		{"GET", "/tree?format=text&path=../sub/deep/..", 200, "└───deep\n"},
+		{"GET", "/tree?path=../../etc", 404, "not found\n"},
+		{"GET", "/tree?path=escape", 403, "path is outside the root\n"},
+		{"GET", "/tree?path=escape/secret", 403, "path is outside the root\n"},
+		{"GET", "/tree?path=missing", 404, "not found\n"},
+		{"GET", "/tree?path=file.txt", 400, "not a directory\n"},
+		{"GET", "/tree?depth=-1", 400, "bad depth\n"},
+		{"GET", "/tree?depth=x", 400, "bad depth\n"},
+		{"GET", "/tree?files=maybe", 400, "bad files\n"},
+		{"GET", "/tree?format=xml", 400, "unknown format\n"},
+		{"POST", "/tree", 405, "method not allowed\n"},
+		{"GET", "/nowhere", 404, "404 page not found\n"},
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.url, nil))
+		if rec.Code != tc.code || rec.Body.String() != tc.body {
+			t.Errorf("%s %s: got %d %q, expected %d %q", tc.method, tc.url, rec.Code, rec.Body.String(), tc.code, tc.body)
+		}
+	}
+}
+
+func TestServeNoFollowLinks(t *testing.T) {
+	// The server is given FollowLinks, yet links out of the root are not
+	// descended into.
+	s := newTestServer(t)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/tree?files=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %q", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("the walk left the root:\n%s", rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"name":"escape"`) {
+		t.Errorf("expected the link in the tree:\n%s", rec.Body.String())
+	}
+}
+
+// denyFS fails to open the directory dir.
+type denyFS struct {
+	fs.FS
+	dir string
+}
+
+func (d denyFS) Open(name string) (fs.File, error) {
+	if name == d.dir {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	}
+	return d.FS.Open(name)
+}
+
+func TestServeUnreadable(t *testing.T) {
+	// An unreadable directory is reported in the tree, which stays valid
+	// JSON.
+	s := newTestServer(t)
+	s.fsys = denyFS{s.fsys, "sub/deep"}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/tree?files=1", nil))
+	var e tree.Entry
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+		t.Fatalf("invalid JSON (%v):\n%s", err, rec.Body.String())
+	}
+	var errs []string
+	for _, c := range e.Children {
+		for _, cc := range c.Children {
+			if cc.Error != "" {
+				errs = append(errs, c.Name+"/"+cc.Name+": "+cc.Error)
+			}
+		}
+	}
+	if expected := "sub/deep: permission denied"; rec.Code != http.StatusOK || strings.Join(errs, ", ") != expected {
+		t.Errorf("expected %d with %q, got %d with %q", http.StatusOK, expected, rec.Code, errs)
+	}
+}
@@ -0,0 +1,239 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// IsArchive reports whether name has an extension OpenArchive understands.
+func IsArchive(name string) bool {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive opens a .zip, .tar or .tar.gz file as a file system.
+// Zip files are read in place. Tar files are listed into a MemFS, the
+// contents of their files being read from the archive again when opened.
+// The returned closer must be closed when the file system is no longer used.
+func OpenArchive(name string) (fs.FS, io.Closer, error) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		r, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, r, nil
+	}
+
+	src := &tarSource{name: name}
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		src.gzip = true
+	case strings.HasSuffix(lower, ".tar"):
+	default:
+		return nil, nil, fmt.Errorf("%s: unknown archive type", name)
+	}
+	c, err := src.openCursor()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	m := NewMemFS()
+	err = readTar(c.tr, m, func(index int, hdr *tar.Header, name string) error {
+		if hdr.Typeflag == tar.TypeReg {
+			// The head is kept for MIME sniffing, which then never goes
+			// back to the archive.
+			head := make([]byte, sniffLen)
+			n, err := io.ReadFull(c.tr, head)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			return m.AddLazy(name, hdr.FileInfo().Mode(), hdr.ModTime, head[:n:n], hdr.Size, func() (io.ReadCloser, error) {
+				return src.open(index)
+			})
+		}
+		return addTarEntry(m, hdr, name, nil)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, src, nil
+}
+
+// ReadTar loads a tar stream into a MemFS, contents included. Archives
+// in files are better opened with OpenArchive, which reads the contents
+// only when they are needed.
+func ReadTar(r io.Reader) (*MemFS, error) {
+	m := NewMemFS()
+	tr := tar.NewReader(r)
+	err := readTar(tr, m, func(index int, hdr *tar.Header, name string) error {
+		var data []byte
+		if hdr.Typeflag == tar.TypeReg {
+			var err error
+			if data, err = io.ReadAll(tr); err != nil {
+				return err
+			}
+		}
+		return addTarEntry(m, hdr, name, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readTar calls add for every entry of tr with a valid name, along with
+// its index among all the entries of the archive. As in tar, an entry
+// replaces an earlier file of the same name in m, such as one appended
+// with tar -r.
+func readTar(tr *tar.Reader, m *MemFS, add func(index int, hdr *tar.Header, name string) error) error {
+	for index := 0; ; index++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Global headers, such as those of git archive, hold metadata
+		// about the archive, not a file.
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." || !fs.ValidPath(name) {
+			continue
+		}
+		m.removeFile(name)
+		if err := add(index, hdr, name); err != nil {
+			return err
+		}
+	}
+}
+
+// addTarEntry adds the entry hdr to m, with data as the contents of a
+// regular file.
+func addTarEntry(m *MemFS, hdr *tar.Header, name string, data []byte) error {
+	if hdr.Typeflag == tar.TypeSymlink {
+		data = []byte(hdr.Linkname)
+	}
+	return m.Add(name, hdr.FileInfo().Mode(), hdr.ModTime, data)
+}
+
+// tarSource reads the contents of files from a tar archive on disk again,
+// so that they aren't held in memory. Reads of entries in archive order
+// share one pass over the archive.
+type tarSource struct {
+	name string
+	gzip bool
+
+	mu    sync.Mutex
+	idle  *tarCursor // a pass left after an entry, to be reused
+	opens int        // passes started to read contents
+}
+
+// tarCursor is a pass over the archive, before the entry at index next.
+type tarCursor struct {
+	f    *os.File
+	gz   *gzip.Reader
+	tr   *tar.Reader
+	next int
+}
+
+func (s *tarSource) openCursor() (*tarCursor, error) {
+	f, err := os.Open(s.name)
+	if err != nil {
+		return nil, err
+	}
+	c := &tarCursor{f: f}
+	var r io.Reader = f
+	if s.gzip {
+		if c.gz, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		r = c.gz
+	}
+	c.tr = tar.NewReader(r)
+	return c, nil
+}
+
+func (c *tarCursor) Close() error {
+	if c.gz != nil {
+		c.gz.Close()
+	}
+	return c.f.Close()
+}
+
+// open returns a reader of the contents of the entry at index.
+func (s *tarSource) open(index int) (io.ReadCloser, error) {
+	s.mu.Lock()
+	c := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+	if c != nil && c.next > index {
+		c.Close()
+		c = nil
+	}
+	if c == nil {
+		s.mu.Lock()
+		s.opens++
+		s.mu.Unlock()
+		var err error
+		if c, err = s.openCursor(); err != nil {
+			return nil, err
+		}
+	}
+	for c.next <= index {
+		if _, err := c.tr.Next(); err != nil {
+			c.Close()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		c.next++
+	}
+	return &tarEntry{Reader: c.tr, c: c, s: s}, nil
+}
+
+// Close closes the pass kept for reuse.
+func (s *tarSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle == nil {
+		return nil
+	}
+	err := s.idle.Close()
+	s.idle = nil
+	return err
+}
+
+// tarEntry reads one entry, handing its pass back to the source on Close.
+type tarEntry struct {
+	io.Reader
+	c *tarCursor
+	s *tarSource
+}
+
+func (e *tarEntry) Close() error {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	if e.s.idle == nil {
+		e.s.idle = e.c
+		return nil
+	}
+	return e.c.Close()
+}
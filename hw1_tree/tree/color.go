@@ -0,0 +1,79 @@
+package tree
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// defaultLSColors are used for the types LS_COLORS doesn't mention,
+// the same as GNU ls defaults.
+const defaultLSColors = "di=01;34:ln=01;36:pi=40;33:so=01;35:bd=40;33;01:cd=40;33;01:or=40;31;01:ex=01;32"
+
+// Colors are the ANSI color codes of entries, in LS_COLORS terms.
+type Colors struct {
+	types map[string]string // di, ln, ex, ...
+	exts  map[string]string // lower-case extension with the dot
+}
+
+// ParseLSColors parses an LS_COLORS value on top of the defaults.
+// Malformed items are skipped.
+func ParseLSColors(s string) *Colors {
+	c := &Colors{types: map[string]string{}, exts: map[string]string{}}
+	for _, spec := range []string{defaultLSColors, s} {
+		for _, item := range strings.Split(spec, ":") {
+			eq := strings.IndexByte(item, '=')
+			if eq <= 0 {
+				continue
+			}
+			key, code := item[:eq], item[eq+1:]
+			if strings.HasPrefix(key, "*.") {
+				c.exts[strings.ToLower(key[1:])] = code
+			} else {
+				c.types[key] = code
+			}
+		}
+	}
+	return c
+}
+
+// paint wraps name in the color of the entry it names.
+func (c *Colors) paint(name string, n Node) string {
+	code := c.code(n)
+	if code == "" || code == "0" || code == "00" {
+		return name
+	}
+	return "\x1b[" + code + "m" + name + "\x1b[0m"
+}
+
+func (c *Colors) code(n Node) string {
+	mode := n.Info.Mode()
+	switch {
+	case n.Broken:
+		return c.types["or"]
+	case mode&fs.ModeSymlink != 0:
+		return c.types["ln"]
+	case mode.IsDir():
+		return c.types["di"]
+	case mode&fs.ModeNamedPipe != 0:
+		return c.types["pi"]
+	case mode&fs.ModeSocket != 0:
+		return c.types["so"]
+	case mode&fs.ModeCharDevice != 0:
+		return c.types["cd"]
+	case mode&fs.ModeDevice != 0:
+		return c.types["bd"]
+	case mode&0111 != 0:
+		return c.types["ex"]
+	}
+	// The longest matching extension wins, so *.tar.gz beats *.gz.
+	name := strings.ToLower(n.Info.Name())
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if code, ok := c.exts[name[i:]]; ok {
+			return code
+		}
+	}
+	return c.types["fi"]
+}
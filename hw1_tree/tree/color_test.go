@@ -0,0 +1,33 @@
+package tree
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+const testColorResult = "├───\x1b[01;34mbin\x1b[0m\n" +
+	"│\t├───\x1b[01;32mrun.sh\x1b[0m (3b)\n" +
+	"│\t└───\x1b[31mx.tar.gz\x1b[0m (empty)\n" +
+	"├───\x1b[35mgopher.PNG\x1b[0m (empty)\n" +
+	"└───notes.txt (empty)\n"
+
+func TestTextColors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bin":          {Mode: fs.ModeDir | 0755},
+		"bin/run.sh":   {Data: []byte("#!\n"), Mode: 0755},
+		"bin/x.tar.gz": {Mode: 0644},
+		"gopher.PNG":   {Mode: 0644},
+		"notes.txt":    {Mode: 0644},
+	}
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.Colors = ParseLSColors("*.png=35:*.gz=33:*.tar.gz=31:bogus")
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true}, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testColorResult {
+		t.Errorf("results not match\nGot:\n%q\nExpected:\n%q", out.String(), testColorResult)
+	}
+}
@@ -0,0 +1,143 @@
+package tree
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+)
+
+// orderedFS is implemented by file systems whose files are read fastest
+// in a given order, such as tar archives that are read from the start
+// again for every file out of order.
+type orderedFS interface {
+	// readOrder returns the position of name in that order, or false for
+	// files read at no cost.
+	readOrder(name string) (int, bool)
+}
+
+// contentItems appends to files the regular files among items whose
+// content is needed: the visited ones and, with CountLines, all of them.
+func (w *walker) contentItems(items []*item, files []*item) []*item {
+	if !w.opts.SniffMIME && !w.opts.CountLines && (w.opts.Hash == NoHash || !w.opts.PrintFiles) {
+		return nil
+	}
+	for _, it := range items {
+		if it.info.Mode().IsRegular() && it.err == nil && (it.visited || w.opts.CountLines) {
+			files = append(files, it)
+		}
+		files = w.contentItems(it.children, files)
+	}
+	return files
+}
+
+// readFiles reads files in parallel, streaming each file once through the
+// hash, the line counter and the MIME sniffer as enabled by the options.
+// Without KeepGoing the first failure in print order is returned.
+func (w *walker) readFiles(files []*item) error {
+	if len(files) == 0 {
+		return nil
+	}
+	n := w.opts.Workers
+	if n < 1 {
+		n = 1
+	}
+	queue := files
+	if o, ok := w.fsys.(orderedFS); ok {
+		// One reader goes through the files in the order of the file
+		// system, so that an archive is read in a single pass.
+		n = 1
+		order := make(map[*item]int, len(files))
+		for _, it := range files {
+			if i, ok := o.readOrder(w.fsPath(it.path)); ok {
+				order[it] = i + 1
+			}
+		}
+		queue = append([]*item(nil), files...)
+		sort.SliceStable(queue, func(i, j int) bool {
+			return order[queue[i]] < order[queue[j]]
+		})
+	}
+	jobs := make(chan *item)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range jobs {
+				if w.ctx.Err() == nil {
+					it.err = w.readFile(it)
+				}
+			}
+		}()
+	}
+	for _, it := range queue {
+		jobs <- it
+	}
+	close(jobs)
+	wg.Wait()
+
+	if !w.opts.KeepGoing {
+		for _, it := range files {
+			if it.err != nil {
+				return it.err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walker) readFile(it *item) error {
+	var h hash.Hash
+	if w.opts.Hash != NoHash && w.opts.PrintFiles && it.visited {
+		h = w.opts.Hash.new()
+	}
+	// The media type may be known already from a MIME filter.
+	sniff := w.opts.SniffMIME && it.visited && it.mime == ""
+	if h == nil && !w.opts.CountLines {
+		if sniff {
+			mime, err := w.sniffMIME(it.path)
+			it.mime = mime
+			return err
+		}
+		return nil
+	}
+
+	f, err := w.fsys.Open(w.fsPath(it.path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var writers []io.Writer
+	var head *headWriter
+	if sniff {
+		head = &headWriter{}
+		writers = append(writers, head)
+	}
+	if h != nil {
+		writers = append(writers, h)
+	}
+	var lines *lineCounter
+	if w.opts.CountLines {
+		// Without a hash, the counter stops reading binary files early,
+		// past the head the sniffer needs.
+		lines = &lineCounter{stopBinary: h == nil}
+		writers = append(writers, lines)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil && err != errBinary {
+		return err
+	}
+
+	if head != nil {
+		it.mime = detectMIME(head.buf)
+	}
+	if h != nil {
+		it.hash = string(w.opts.Hash) + ":" + hex.EncodeToString(h.Sum(nil))
+	}
+	if lines != nil {
+		it.lines, it.binary = lines.result()
+	}
+	return nil
+}
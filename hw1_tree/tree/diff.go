@@ -0,0 +1,180 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DiffStatus tells how an entry differs between two trees.
+type DiffStatus byte
+
+const (
+	Unchanged DiffStatus = ' '
+	Added     DiffStatus = '+'
+	Removed   DiffStatus = '-'
+	Changed   DiffStatus = '~' // size, mode, mtime or, when hashed, content differ
+)
+
+// DiffOptions control how trees are compared and printed.
+type DiffOptions struct {
+	IgnoreTime    bool  // don't report entries whose only change is the mtime
+	HideUnchanged bool  // don't print subtrees without changes
+	Units         Units // how sizes are formatted
+}
+
+// DiffEntry is a node of the merged tree built by Diff.
+type DiffEntry struct {
+	Name     string
+	Status   DiffStatus
+	Old, New *Entry // nil for added and removed entries respectively
+	Children []*DiffEntry
+
+	changes bool // Status or any descendant's is not Unchanged
+}
+
+// HasChanges reports whether d or anything below it changed.
+func (d *DiffEntry) HasChanges() bool {
+	return d.changes
+}
+
+// Diff merges the trees before and after, as returned by Collect.
+func Diff(before, after *Entry, opts DiffOptions) *DiffEntry {
+	d := &DiffEntry{Name: after.Name, Old: before, New: after}
+	d.Status = compareEntries(before, after, opts)
+	d.Children = diffChildren(before.Children, after.Children, opts)
+	d.finish()
+	return d
+}
+
+func diffChildren(before, after []*Entry, opts DiffOptions) []*DiffEntry {
+	byName := map[string]*DiffEntry{}
+	for _, e := range before {
+		byName[e.Name] = &DiffEntry{Name: e.Name, Old: e}
+	}
+	for _, e := range after {
+		if d, ok := byName[e.Name]; ok {
+			d.New = e
+		} else {
+			byName[e.Name] = &DiffEntry{Name: e.Name, New: e}
+		}
+	}
+
+	var merged []*DiffEntry
+	for _, d := range byName {
+		switch {
+		case d.Old == nil:
+			d.Status = Added
+			d.Children = diffChildren(nil, d.New.Children, opts)
+		case d.New == nil:
+			d.Status = Removed
+			d.Children = diffChildren(d.Old.Children, nil, opts)
+		default:
+			d.Status = compareEntries(d.Old, d.New, opts)
+			d.Children = diffChildren(d.Old.Children, d.New.Children, opts)
+		}
+		d.finish()
+		merged = append(merged, d)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name < merged[j].Name
+	})
+	return merged
+}
+
+// compareEntries compares two entries present in both trees. Directories
+// are compared by type and mode only, their contents are compared as
+// children.
+func compareEntries(a, b *Entry, opts DiffOptions) DiffStatus {
+	if a.Type != b.Type || a.Mode != b.Mode || a.Target != b.Target {
+		return Changed
+	}
+	if a.Type == "dir" {
+		return Unchanged
+	}
+	if a.Size != b.Size || a.Hash != b.Hash || (!opts.IgnoreTime && !a.ModTime.Equal(b.ModTime)) {
+		return Changed
+	}
+	return Unchanged
+}
+
+func (d *DiffEntry) finish() {
+	d.changes = d.Status != Unchanged
+	for _, c := range d.Children {
+		d.changes = d.changes || c.changes
+	}
+}
+
+// WriteDiff prints d as a tree with a status column: "+" for added,
+// "-" for removed, "~" for changed and " " for unchanged entries. The
+// root is printed first when it changed itself, such as its mode.
+func WriteDiff(out io.Writer, d *DiffEntry, opts DiffOptions) error {
+	if d.Status != Unchanged {
+		if _, err := fmt.Fprintf(out, "%c %s%s\n", d.Status, d.Name, d.details(opts)); err != nil {
+			return err
+		}
+	}
+	return writeDiffChildren(out, d, nil, opts)
+}
+
+func writeDiffChildren(out io.Writer, d *DiffEntry, ancestors []bool, opts DiffOptions) error {
+	children := d.Children
+	if opts.HideUnchanged {
+		children = nil
+		for _, c := range d.Children {
+			if c.changes {
+				children = append(children, c)
+			}
+		}
+	}
+	for i, c := range children {
+		isLast := i == len(children)-1
+		_, err := fmt.Fprintf(out, "%c %s%s%s\n", c.Status, DefaultStyle.prefix(ancestors, isLast), c.Name, c.details(opts))
+		if err != nil {
+			return err
+		}
+		if err := writeDiffChildren(out, c, append(ancestors, isLast), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// details returns the size of a file, or for changed entries what changed.
+func (d *DiffEntry) details(opts DiffOptions) string {
+	e := d.New
+	if e == nil {
+		e = d.Old
+	}
+	if d.Status != Changed {
+		switch {
+		case e.Type == "dir":
+			return ""
+		case e.Size == 0:
+			return " (empty)"
+		}
+		return " (" + FormatSize(e.Size, opts.Units) + ")"
+	}
+
+	var changes []string
+	if d.Old.Type != d.New.Type {
+		changes = append(changes, d.Old.Type+" -> "+d.New.Type)
+	}
+	if d.Old.Size != d.New.Size {
+		changes = append(changes, FormatSize(d.Old.Size, opts.Units)+" -> "+FormatSize(d.New.Size, opts.Units))
+	}
+	if d.Old.Size == d.New.Size && d.Old.Hash != d.New.Hash {
+		changes = append(changes, "content")
+	}
+	if d.Old.Mode != d.New.Mode {
+		changes = append(changes, d.Old.Mode+" -> "+d.New.Mode)
+	}
+	if d.Old.Target != d.New.Target {
+		changes = append(changes, "target "+d.Old.Target+" -> "+d.New.Target)
+	}
+	if !opts.IgnoreTime && !d.Old.ModTime.Equal(d.New.ModTime) && d.New.Type != "dir" {
+		changes = append(changes, "mtime")
+	}
+	return " (" + strings.Join(changes, ", ") + ")"
+}
@@ -0,0 +1,110 @@
+package tree
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+const testDiffResult = `+ ├───added
++ │	└───x.txt (empty)
+  ├───same
+  │	└───a.txt (3b)
+  ├───src
+~ │	├───main.go (3b -> 5b)
+- │	├───old.go (2b)
+~ │	└───run.sh (-rw-r--r-- -> -rwxr-xr-x)
+- └───zz.txt (empty)
+`
+
+const testDiffHiddenResult = `+ ├───added
++ │	└───x.txt (empty)
+  ├───src
+~ │	├───main.go (3b -> 5b)
+- │	├───old.go (2b)
+~ │	└───run.sh (-rw-r--r-- -> -rwxr-xr-x)
+- └───zz.txt (empty)
+`
+
+func TestDiff(t *testing.T) {
+	before := fstest.MapFS{
+		"same/a.txt":  {Data: []byte("abc")},
+		"src/main.go": {Data: []byte("abc")},
+		"src/old.go":  {Data: []byte("ab")},
+		"src/run.sh":  {Mode: 0644},
+		"zz.txt":      {},
+	}
+	after := fstest.MapFS{
+		"same/a.txt":  {Data: []byte("abc")},
+		"src/main.go": {Data: []byte("abcde")},
+		"src/run.sh":  {Mode: 0755},
+		"added/x.txt": {},
+	}
+	a, err := CollectFS(before, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CollectFS(after, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := Diff(a, b, DiffOptions{})
+	if !d.HasChanges() {
+		t.Errorf("expected changes")
+	}
+	out := new(bytes.Buffer)
+	if err := WriteDiff(out, d, DiffOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDiffResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDiffResult)
+	}
+
+	out.Reset()
+	if err := WriteDiff(out, d, DiffOptions{HideUnchanged: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDiffHiddenResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDiffHiddenResult)
+	}
+
+	if Diff(a, a, DiffOptions{}).HasChanges() {
+		t.Errorf("expected no changes between a tree and itself")
+	}
+}
+
+const testDiffRootResult = `~ . (drwxr-xr-x -> drwx------)
+  └───a.txt (3b)
+`
+
+func TestDiffRoot(t *testing.T) {
+	before := fstest.MapFS{
+		".":     {Mode: fs.ModeDir | 0755},
+		"a.txt": {Data: []byte("abc")},
+	}
+	after := fstest.MapFS{
+		".":     {Mode: fs.ModeDir | 0700},
+		"a.txt": {Data: []byte("abc")},
+	}
+	a, err := CollectFS(before, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CollectFS(after, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Diff(a, b, DiffOptions{})
+	if !d.HasChanges() {
+		t.Errorf("expected changes")
+	}
+	out := new(bytes.Buffer)
+	if err := WriteDiff(out, d, DiffOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDiffRootResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDiffRootResult)
+	}
+}
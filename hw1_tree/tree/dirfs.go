@@ -0,0 +1,31 @@
+package tree
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ReadLinkFS is implemented by file systems with symlinks. Stat on such
+// a file system follows links, while ReadDir entries describe the links.
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+// DirFS is os.DirFS that also implements ReadLinkFS.
+func DirFS(dir string) fs.FS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+func (d dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Readlink(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
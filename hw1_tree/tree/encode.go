@@ -0,0 +1,278 @@
+package tree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a node of the nested tree emitted by WriteJSON and WriteYAML.
+// Directory sizes are always zero, so that the output doesn't depend on
+// the underlying filesystem.
+type Entry struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Mode     string    `json:"mode"`
+	ModTime  time.Time `json:"mtime"`
+	Target   string    `json:"target,omitempty"` // symlink target, with Options.Symlinks
+	Broken   bool      `json:"broken,omitempty"`
+	Hash     string    `json:"hash,omitempty"`  // with Options.Hash
+	Lines    int64     `json:"lines,omitempty"` // with Options.CountLines, totalled for directories
+	Binary   bool      `json:"binary,omitempty"`
+	MIME     string    `json:"mime,omitempty"` // with Options.SniffMIME
+	Error    string    `json:"error,omitempty"`
+	Children []*Entry  `json:"children,omitempty"`
+	Omitted  int       `json:"omitted,omitempty"` // children left out by Options.MaxEntries
+}
+
+func newEntry(name string, info fs.FileInfo) *Entry {
+	e := &Entry{
+		Name:    name,
+		Type:    fileType(info.Mode()),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime().UTC(),
+	}
+	if !info.IsDir() {
+		e.Size = info.Size()
+	}
+	return e
+}
+
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	default:
+		return "other"
+	}
+}
+
+func nodeEntry(n Node) *Entry {
+	e := newEntry(n.Info.Name(), n.Info)
+	e.Target = n.LinkTarget
+	e.Broken = n.Broken
+	e.Hash = n.Hash
+	e.Lines = n.Lines
+	e.Binary = n.Binary
+	e.MIME = n.MIME
+	if n.Err != nil {
+		e.Error = errorMark(n.Err)
+	}
+	return e
+}
+
+// Collector is a Visitor that assembles the visited nodes into Entries.
+type Collector struct {
+	Root  *Entry
+	stack []*Entry // open directories, stack[0] is the root
+}
+
+// NewCollector returns a Collector whose root entry describes root.
+func NewCollector(root string, info fs.FileInfo) *Collector {
+	e := newEntry(root, info)
+	return &Collector{Root: e, stack: []*Entry{e}}
+}
+
+func (c *Collector) Visit(n Node) error {
+	c.stack = c.stack[:n.Depth+1]
+	if n.Info == nil {
+		c.stack[n.Depth].Omitted = n.Omitted
+		return nil
+	}
+	e := nodeEntry(n)
+	parent := c.stack[n.Depth]
+	parent.Children = append(parent.Children, e)
+	if n.Info.IsDir() {
+		c.stack = append(c.stack, e)
+	}
+	return nil
+}
+
+// Collect walks root and returns it as a nested Entry. With KeepGoing set
+// the partial tree is returned together with the collected Errors.
+func Collect(root string, opts Options) (*Entry, error) {
+	return CollectContext(context.Background(), root, opts)
+}
+
+// CollectContext is Collect that stops reading directories once ctx is
+// done, returning the partial tree together with the error of ctx, as
+// WalkContext does.
+func CollectContext(ctx context.Context, root string, opts Options) (*Entry, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, &RootError{Path: root, Err: err}
+	}
+	if !info.IsDir() {
+		return nil, &RootError{Path: root, Err: ErrNotDir}
+	}
+	return collect(ctx, DirFS(root), ".", root, opts)
+}
+
+// CollectFS is Collect for the directory root of fsys.
+func CollectFS(fsys fs.FS, root string, opts Options) (*Entry, error) {
+	return CollectFSContext(context.Background(), fsys, root, opts)
+}
+
+// CollectFSContext is CollectContext for the directory root of fsys.
+func CollectFSContext(ctx context.Context, fsys fs.FS, root string, opts Options) (*Entry, error) {
+	return collect(ctx, fsys, root, root, opts)
+}
+
+func collect(ctx context.Context, fsys fs.FS, root, name string, opts Options) (*Entry, error) {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, &RootError{Path: name, Err: err}
+	}
+	c := NewCollector(name, info)
+	err = walk(ctx, fsys, root, name, opts, c)
+	if _, ok := err.(Errors); err != nil && !ok && err != ctx.Err() {
+		return nil, err
+	}
+	return c.Root, err
+}
+
+// WriteJSON writes e as indented JSON.
+func WriteJSON(out io.Writer, e *Entry) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// JSONWriter is a Visitor that writes the nodes as they are visited, in
+// the compact form of the JSON written by WriteJSON for the same tree.
+// The document is complete once Finish is called, which Walk does after
+// a successful walk.
+type JSONWriter struct {
+	out   io.Writer
+	err   error
+	open  []*jsonDir // directories whose children are being written
+	next  *Entry     // the last entry, written once it is known whether children follow
+	depth int        // of next, -1 for the root
+}
+
+type jsonDir struct {
+	children int
+	omitted  int
+}
+
+// NewJSONWriter returns a JSONWriter whose root entry describes root.
+func NewJSONWriter(out io.Writer, root string, info fs.FileInfo) *JSONWriter {
+	return &JSONWriter{out: out, next: newEntry(root, info), depth: -1}
+}
+
+func (w *JSONWriter) Visit(n Node) error {
+	w.flush(n.Depth)
+	if n.Info == nil {
+		w.open[n.Depth].omitted = n.Omitted
+		return w.err
+	}
+	w.next, w.depth = nodeEntry(n), n.Depth
+	return w.err
+}
+
+func (w *JSONWriter) Finish(Stats) error {
+	w.flush(-1)
+	w.write([]byte("\n"))
+	return w.err
+}
+
+// flush writes the pending entry and closes the directories above depth,
+// the depth of the next node.
+func (w *JSONWriter) flush(depth int) {
+	if w.next != nil {
+		if len(w.open) > 0 {
+			if parent := w.open[len(w.open)-1]; parent.children > 0 {
+				w.write([]byte(","))
+			}
+			w.open[len(w.open)-1].children++
+		}
+		b, err := json.Marshal(w.next)
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		if w.next.Type == "dir" && depth > w.depth {
+			// Children follow, so the entry stays open.
+			w.write(append(b[:len(b)-1], `,"children":[`...))
+			w.open = append(w.open, &jsonDir{})
+		} else {
+			w.write(b)
+		}
+		w.next = nil
+	}
+	for len(w.open) > depth+1 {
+		d := w.open[len(w.open)-1]
+		w.open = w.open[:len(w.open)-1]
+		if d.omitted > 0 {
+			w.write([]byte(fmt.Sprintf(`],"omitted":%d}`, d.omitted)))
+		} else {
+			w.write([]byte("]}"))
+		}
+	}
+}
+
+func (w *JSONWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.out.Write(b)
+	}
+}
+
+// WriteYAML writes e as a YAML document.
+func WriteYAML(out io.Writer, e *Entry) error {
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeYAMLEntry(&b, e, "", "")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writeYAMLEntry writes the fields of e. The first line starts with first,
+// the following ones with indent.
+func writeYAMLEntry(b *strings.Builder, e *Entry, first, indent string) {
+	fmt.Fprintf(b, "%sname: %s\n", first, strconv.Quote(e.Name))
+	fmt.Fprintf(b, "%stype: %s\n", indent, e.Type)
+	fmt.Fprintf(b, "%ssize: %d\n", indent, e.Size)
+	fmt.Fprintf(b, "%smode: %s\n", indent, strconv.Quote(e.Mode))
+	fmt.Fprintf(b, "%smtime: %s\n", indent, e.ModTime.Format(time.RFC3339Nano))
+	if e.Target != "" {
+		fmt.Fprintf(b, "%starget: %s\n", indent, strconv.Quote(e.Target))
+	}
+	if e.Broken {
+		fmt.Fprintf(b, "%sbroken: true\n", indent)
+	}
+	if e.Hash != "" {
+		fmt.Fprintf(b, "%shash: %s\n", indent, e.Hash)
+	}
+	if e.Lines != 0 {
+		fmt.Fprintf(b, "%slines: %d\n", indent, e.Lines)
+	}
+	if e.Binary {
+		fmt.Fprintf(b, "%sbinary: true\n", indent)
+	}
+	if e.MIME != "" {
+		fmt.Fprintf(b, "%smime: %s\n", indent, strconv.Quote(e.MIME))
+	}
+	if e.Error != "" {
+		fmt.Fprintf(b, "%serror: %s\n", indent, strconv.Quote(e.Error))
+	}
+	if e.Omitted > 0 {
+		fmt.Fprintf(b, "%somitted: %d\n", indent, e.Omitted)
+	}
+	if len(e.Children) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%schildren:\n", indent)
+	for _, child := range e.Children {
+		writeYAMLEntry(b, child, indent+"  - ", indent+"    ")
+	}
+}
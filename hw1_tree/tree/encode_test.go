@@ -0,0 +1,190 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var fixtureTime = time.Date(2021, 9, 8, 12, 0, 0, 0, time.UTC)
+
+// makeFixture creates a small tree with fixed modes and mtimes.
+func makeFixture(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "root")
+	files := map[string]string{
+		"b/x.txt":   "hello",
+		"b/y/z.txt": "",
+		"a.txt":     "some\ntext\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if info.IsDir() {
+			mode = 0755
+		}
+		return os.Chmod(p, mode)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b/x.txt", "b/y/z.txt", "b/y", "b", ""} {
+		if err := os.Chtimes(filepath.Join(root, name), fixtureTime, fixtureTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const testJSONResult = `{
+  "name": "root",
+  "type": "dir",
+  "size": 0,
+  "mode": "drwxr-xr-x",
+  "mtime": "2021-09-08T12:00:00Z",
+  "children": [
+    {
+      "name": "a.txt",
+      "type": "file",
+      "size": 10,
+      "mode": "-rw-r--r--",
+      "mtime": "2021-09-08T12:00:00Z"
+    },
+    {
+      "name": "b",
+      "type": "dir",
+      "size": 0,
+      "mode": "drwxr-xr-x",
+      "mtime": "2021-09-08T12:00:00Z",
+      "children": [
+        {
+          "name": "x.txt",
+          "type": "file",
+          "size": 5,
+          "mode": "-rw-r--r--",
+          "mtime": "2021-09-08T12:00:00Z"
+        },
+        {
+          "name": "y",
+          "type": "dir",
+          "size": 0,
+          "mode": "drwxr-xr-x",
+          "mtime": "2021-09-08T12:00:00Z",
+          "children": [
+            {
+              "name": "z.txt",
+              "type": "file",
+              "size": 0,
+              "mode": "-rw-r--r--",
+              "mtime": "2021-09-08T12:00:00Z"
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+const testYAMLResult = `---
+name: "root"
+type: dir
+size: 0
+mode: "drwxr-xr-x"
+mtime: 2021-09-08T12:00:00Z
+children:
+  - name: "b"
+    type: dir
+    size: 0
+    mode: "drwxr-xr-x"
+    mtime: 2021-09-08T12:00:00Z
+    children:
+      - name: "y"
+        type: dir
+        size: 0
+        mode: "drwxr-xr-x"
+        mtime: 2021-09-08T12:00:00Z
+`
+
+func TestWriteJSON(t *testing.T) {
+	root := makeFixture(t)
+	e, err := Collect(root, Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Name = "root"
+	out := new(bytes.Buffer)
+	if err := WriteJSON(out, e); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testJSONResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testJSONResult)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	root := makeFixture(t)
+	e, err := Collect(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Name = "root"
+	out := new(bytes.Buffer)
+	if err := WriteYAML(out, e); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testYAMLResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testYAMLResult)
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	root := makeFixture(t)
+	info, err := os.Stat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []Options{
+		{},
+		{PrintFiles: true},
+		{PrintFiles: true, MaxEntries: 1},
+		{PrintFiles: true, MaxDepth: 1},
+		{Include: []string{"nothing"}, Prune: true},
+	} {
+		e, err := Collect(root, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := new(bytes.Buffer)
+		if err := WriteJSON(expected, e); err != nil {
+			t.Fatal(err)
+		}
+		compact := new(bytes.Buffer)
+		if err := json.Compact(compact, expected.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		compact.WriteString("\n")
+
+		out := new(bytes.Buffer)
+		if err := Walk(root, opts, NewJSONWriter(out, root, info)); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != compact.String() {
+			t.Errorf("%+v: results not match\nGot:\n%v\nExpected:\n%v", opts, out.String(), compact.String())
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package tree
+
+import "os"
+
+// fileID identifies a file by device and inode.
+type fileID struct {
+	dev, ino uint64
+}
+
+// getFileID isn't supported here, so symlink loops are cut by MaxDepth only.
+func getFileID(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+func getOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,297 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileClass selects entries by type for Options.Types.
+type FileClass string
+
+const (
+	ClassFile    FileClass = "file" // regular files
+	ClassDir     FileClass = "dir"
+	ClassSymlink FileClass = "symlink"
+	ClassSocket  FileClass = "socket"
+	ClassExec    FileClass = "exec" // regular files executable by anyone
+)
+
+func (c FileClass) check() error {
+	switch c {
+	case ClassFile, ClassDir, ClassSymlink, ClassSocket, ClassExec:
+		return nil
+	}
+	return fmt.Errorf("unknown file type %q", string(c))
+}
+
+func (c FileClass) match(mode fs.FileMode) bool {
+	switch c {
+	case ClassFile:
+		return mode.IsRegular()
+	case ClassDir:
+		return mode.IsDir()
+	case ClassSymlink:
+		return mode&fs.ModeSymlink != 0
+	case ClassSocket:
+		return mode&fs.ModeSocket != 0
+	case ClassExec:
+		return mode.IsRegular() && mode&0111 != 0
+	}
+	return false
+}
+
+type filter struct {
+	include    []string
+	exclude    []string
+	hideHidden bool
+
+	predicates     bool // any of the fields below is set
+	minSize        int64
+	maxSize        int64
+	modifiedAfter  time.Time
+	modifiedBefore time.Time
+	types          []FileClass
+	includeMIME    []string
+	excludeMIME    []string
+}
+
+func newFilter(opts Options) (*filter, error) {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if err := checkGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+	for _, pattern := range append(append([]string(nil), opts.IncludeMIME...), opts.ExcludeMIME...) {
+		if err := checkMIMEPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range opts.Types {
+		if err := c.check(); err != nil {
+			return nil, err
+		}
+	}
+	f := &filter{
+		include:    opts.Include,
+		exclude:    opts.Exclude,
+		hideHidden: opts.HideHidden,
+
+		minSize:        opts.MinSize,
+		maxSize:        opts.MaxSize,
+		modifiedAfter:  opts.ModifiedAfter,
+		modifiedBefore: opts.ModifiedBefore,
+		types:          opts.Types,
+		includeMIME:    opts.IncludeMIME,
+		excludeMIME:    opts.ExcludeMIME,
+	}
+	f.predicates = f.minSize > 0 || f.maxSize > 0 || !f.modifiedAfter.IsZero() || !f.modifiedBefore.IsZero() || len(f.types) > 0 ||
+		len(f.includeMIME) > 0 || len(f.excludeMIME) > 0
+	return f, nil
+}
+
+// match reports whether info and the media type of a file satisfy the
+// size, time, type and MIME predicates. Without predicates everything
+// matches.
+func (f *filter) match(info fs.FileInfo, mime string) bool {
+	if !f.predicates {
+		return true
+	}
+	if len(f.types) > 0 {
+		matched := false
+		for _, c := range f.types {
+			if c.match(info.Mode()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	} else if info.IsDir() {
+		return false
+	}
+	if !info.IsDir() {
+		if info.Size() < f.minSize || f.maxSize > 0 && info.Size() > f.maxSize {
+			return false
+		}
+	}
+	if !f.modifiedAfter.IsZero() && !info.ModTime().After(f.modifiedAfter) {
+		return false
+	}
+	if !f.modifiedBefore.IsZero() && !info.ModTime().Before(f.modifiedBefore) {
+		return false
+	}
+	if info.IsDir() {
+		return true
+	}
+	for _, pattern := range f.excludeMIME {
+		if matchMIME(pattern, mime) {
+			return false
+		}
+	}
+	if len(f.includeMIME) == 0 {
+		return true
+	}
+	for _, pattern := range f.includeMIME {
+		if matchMIME(pattern, mime) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether the entry at p, relative to the root, passes the
+// hidden file, include/exclude and .gitignore rules in effect.
+func (f *filter) keep(p string, isDir bool, ignore []ignoreRule) bool {
+	if f.hideHidden && strings.HasPrefix(path.Base(p), ".") {
+		return false
+	}
+	for _, pattern := range f.exclude {
+		if matchPattern(pattern, p) {
+			return false
+		}
+	}
+	if !isDir && len(f.include) > 0 {
+		included := false
+		for _, pattern := range f.include {
+			if matchPattern(pattern, p) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	ignored := false
+	for _, rule := range ignore {
+		if rule.match(p, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return !ignored
+}
+
+func checkGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchPattern matches a pattern without a slash against the base name,
+// any other pattern against the whole path.
+func matchPattern(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		return matchGlob(pattern, path.Base(p))
+	}
+	return matchGlob(strings.TrimPrefix(pattern, "/"), p)
+}
+
+// matchGlob is path.Match where a "**" segment matches any number of
+// path segments, including none.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreRule is a single line of a .gitignore file.
+type ignoreRule struct {
+	base     string // directory of the .gitignore, relative to the root
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // relative to base rather than matching at any level
+}
+
+func (r ignoreRule) match(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		p = p[len(r.base)+1:]
+	}
+	if r.anchored {
+		return matchGlob(r.pattern, p)
+	}
+	return matchGlob(r.pattern, path.Base(p))
+}
+
+// loadIgnore returns rules extended with the .gitignore of dir, which is
+// fsDir in fsys, if any. A missing or unreadable .gitignore is not an error.
+func loadIgnore(fsys fs.FS, fsDir, dir string, rules []ignoreRule) []ignoreRule {
+	f, err := fsys.Open(path.Join(fsDir, ".gitignore"))
+	if err != nil {
+		return rules
+	}
+	defer f.Close()
+
+	var own []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rule, ok := parseIgnoreLine(sc.Text()); ok {
+			rule.base = dir
+			own = append(own, rule)
+		}
+	}
+	if len(own) == 0 {
+		return rules
+	}
+	return append(append([]ignoreRule(nil), rules...), own...)
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var r ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" || checkGlob(line) != nil {
+		return r, false
+	}
+	r.pattern = line
+	return r, true
+}
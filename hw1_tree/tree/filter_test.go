@@ -0,0 +1,166 @@
+package tree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"*.png", "gopher.png", true},
+		{"a/*.txt", "a/b.txt", true},
+		{"a/*.txt", "a/b/c.txt", false},
+		{"a/**/c.txt", "a/c.txt", true},
+		{"a/**/c.txt", "a/b/d/c.txt", true},
+		{"**/ipsum", "static/a_lorem/ipsum", true},
+		{"**", "any/thing", true},
+		{"static/**", "zline/lorem", false},
+	}
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.name); got != c.match {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.match)
+		}
+	}
+}
+
+const testFilterResult = `├───static
+│	├───a_lorem
+│	│	└───ipsum
+│	│		└───gopher.png (70372b)
+│	└───z_lorem
+│		└───ipsum
+│			└───gopher.png (70372b)
+└───zline
+	└───lorem
+		└───ipsum
+			└───gopher.png (70372b)
+`
+
+func TestWalkFilter(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := Options{PrintFiles: true, Include: []string{"**/ipsum/*.png"}, Prune: true}
+	if err := Walk("../testdata", opts, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testFilterResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testFilterResult)
+	}
+
+	err := Walk("../testdata", Options{Exclude: []string{"[a-"}}, NewTextPrinter(out))
+	if err == nil {
+		t.Errorf("expected error for bad pattern")
+	}
+}
+
+const testGitIgnoreResult = `├───.gitignore (16b)
+├───build
+│	└───out (empty)
+├───keep.log (empty)
+├───src
+│	├───.gitignore (11b)
+│	├───main.go (empty)
+│	└───vendor
+│		└───lib.go (empty)
+└───x.go (empty)
+`
+
+func TestWalkGitIgnore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "*.log\n!keep.log\n",
+		"src/.gitignore":       "/build\n*.o\n",
+		"keep.log":             "",
+		"debug.log":            "",
+		"x.go":                 "",
+		"build/out":            "",
+		"src/main.go":          "",
+		"src/main.o":           "",
+		"src/build/out":        "",
+		"src/vendor/lib.go":    "",
+		"src/vendor/build/x":   "",
+		"src/vendor/cache.o":   "",
+		"src/vendor/trace.log": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := new(bytes.Buffer)
+	opts := Options{PrintFiles: true, GitIgnore: true, Exclude: []string{"**/vendor/build"}, Prune: true}
+	if err := Walk(root, opts, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testGitIgnoreResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testGitIgnoreResult)
+	}
+}
+
+func TestWalkPredicates(t *testing.T) {
+	root := t.TempDir()
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []struct {
+		name  string
+		size  int
+		mode  os.FileMode
+		mtime time.Time
+	}{
+		{"big.bin", 2048, 0644, fixtureTime},
+		{"old/big.bin", 4096, 0644, old},
+		{"old/small.txt", 10, 0644, old},
+		{"src/run.sh", 100, 0755, fixtureTime},
+		{"src/main.go", 500, 0644, fixtureTime},
+		{"empty/x", 0, 0644, fixtureTime},
+	}
+	for _, f := range files {
+		p := filepath.Join(root, f.name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, f.size), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, f.mtime, f.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("src/run.sh", filepath.Join(root, "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{MinSize: 1024}, "├───big.bin (2048b)\n└───old\n\t└───big.bin (4096b)\n"},
+		{Options{MinSize: 1024, ModifiedAfter: old}, "└───big.bin (2048b)\n"},
+		{Options{MaxSize: 100, ModifiedBefore: fixtureTime}, "└───old\n\t└───small.txt (10b)\n"},
+		{Options{Types: []FileClass{ClassExec, ClassSymlink}}, "├───run (10b)\n└───src\n\t└───run.sh (100b)\n"},
+		{Options{Types: []FileClass{ClassDir}, ModifiedAfter: old.Add(time.Hour)}, "├───empty\n├───old\n└───src\n"},
+	}
+	for _, c := range cases {
+		out := new(bytes.Buffer)
+		c.opts.PrintFiles = true
+		if err := Walk(root, c.opts, NewTextPrinter(out)); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.expected {
+			t.Errorf("%+v: results not match\nGot:\n%v\nExpected:\n%v", c.opts, out.String(), c.expected)
+		}
+	}
+
+	if err := Walk(root, Options{Types: []FileClass{"pipe"}}, NewTextPrinter(new(bytes.Buffer))); err == nil {
+		t.Errorf("expected error for unknown file type")
+	}
+}
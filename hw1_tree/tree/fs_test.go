@@ -0,0 +1,258 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const testFSResult = `├───a
+│	├───b
+│	│	└───c.txt (3b)
+│	└───empty.txt (empty)
+└───z.txt (5b)
+`
+
+func TestWalkMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/c.txt":   {Data: []byte("abc")},
+		"a/empty.txt": {},
+		"z.txt":       {Data: []byte("hello")},
+	}
+	out := new(bytes.Buffer)
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testFSResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testFSResult)
+	}
+
+	out.Reset()
+	if err := WalkFS(fsys, "a", Options{}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "└───b\n" {
+		t.Errorf("results not match\nGot:\n%v", out.String())
+	}
+}
+
+func TestWalkZip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range []struct{ name, data string }{
+		{"z.txt", "hello"},
+		{"a/b/c.txt", "abc"},
+		{"a/empty.txt", ""},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := WalkFS(zr, ".", Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testFSResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testFSResult)
+	}
+}
+
+// writeTestTar writes a small tar archive to w, with data in z.txt.
+func writeTestTar(t *testing.T, w io.Writer, data string) {
+	tw := tar.NewWriter(w)
+	headers := []struct {
+		hdr  tar.Header
+		data string
+	}{
+		{tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "abc"}}, ""},
+		{tar.Header{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{tar.Header{Name: "a/b/c.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 3}, "abc"},
+		{tar.Header{Name: "a/empty.txt", Typeflag: tar.TypeReg, Mode: 0644}, ""},
+		{tar.Header{Name: "z.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}, data},
+		{tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "z.txt", Mode: 0777}, ""},
+	}
+	for _, h := range headers {
+		if h.hdr.Typeflag != tar.TypeXGlobalHeader {
+			h.hdr.ModTime = tarTime
+		}
+		if err := tw.WriteHeader(&h.hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(h.data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var tarTime = time.Date(2021, 9, 8, 0, 0, 0, 0, time.UTC)
+
+func TestReadTar(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writeTestTar(t, buf, "hello")
+	mtime := tarTime
+
+	m, err := ReadTar(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(m, "a/b/c.txt", "a/empty.txt", "z.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := WalkFS(m, ".", Options{PrintFiles: true, Symlinks: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "├───a\n│\t├───b\n│\t│\t└───c.txt (3b)\n│\t└───empty.txt (empty)\n├───link -> z.txt\n└───z.txt (5b)\n"
+	if out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+
+	info, err := fs.Stat(m, "a")
+	if err != nil || !info.IsDir() || !info.ModTime().Equal(mtime) {
+		t.Errorf("unexpected info for a: %v, %v", info, err)
+	}
+}
+
+func TestOpenArchiveTar(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.tar.gz")
+	write := func(data string) {
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := gzip.NewWriter(f)
+		writeTestTar(t, gz, data)
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Past its first bytes, a file is read from the archive when it is
+	// read, not when the archive is listed.
+	pad := strings.Repeat(".", sniffLen)
+	write(pad + "hello")
+	m, closer, err := OpenArchive(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	write(pad + "HELLO")
+	data, err := fs.ReadFile(m, "z.txt")
+	if err != nil || string(data) != pad+"HELLO" {
+		t.Errorf("expected the contents of the archive on disk, got %q, %v", data, err)
+	}
+	if err := fstest.TestFS(m, "a/b/c.txt", "a/empty.txt", "z.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenArchiveOnePass(t *testing.T) {
+	// The files are stored in the reverse of the walk order, spread over
+	// directories, and still read in a single pass over the archive.
+	name := filepath.Join(t.TempDir(), "a.tar")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	data := []byte(strings.Repeat("line\n", sniffLen))
+	for i := 99; i >= 0; i-- {
+		hdr := &tar.Header{Name: fmt.Sprintf("d%d/f%02d.txt", i%3, i), Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	m, closer, err := OpenArchive(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	src := closer.(*tarSource)
+	for i, opts := range []Options{
+		{PrintFiles: true, Hash: SHA256, Workers: 4},
+		{CountLines: true, Workers: 4},
+		{PrintFiles: true, SniffMIME: true, Workers: 4},
+	} {
+		src.opens = 0
+		if err := WalkFS(m, ".", opts, VisitorFunc(func(Node) error { return nil })); err != nil {
+			t.Fatal(err)
+		}
+		expected := 1
+		if opts.SniffMIME {
+			expected = 0
+		}
+		if src.opens != expected {
+			t.Errorf("walk %d: expected %d passes over the archive, got %d", i, expected, src.opens)
+		}
+	}
+}
+
+func TestReadTarRepeated(t *testing.T) {
+	// Later entries win, as when files are appended with tar -r.
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, data := range []string{"old", "newer"} {
+		hdr := &tar.Header{Name: "a/x.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "a.tar")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadTar(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lazy, closer, err := OpenArchive(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	for _, fsys := range []fs.FS{m, lazy} {
+		data, err := fs.ReadFile(fsys, "a/x.txt")
+		if err != nil || string(data) != "newer" {
+			t.Errorf("expected the last entry, got %q, %v", data, err)
+		}
+		entries, err := fs.ReadDir(fsys, "a")
+		if err != nil || len(entries) != 1 {
+			t.Errorf("expected one entry in a, got %v, %v", entries, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package tree
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GraphOptions control the diagrams written by WriteDOT and WriteMermaid.
+// Use Options.MaxDepth to keep large trees readable.
+type GraphOptions struct {
+	Sizes bool  // label files with their sizes
+	Units Units // how sizes are formatted
+}
+
+// graphNode is a node of a diagram, identified by its path.
+type graphNode struct {
+	path   string
+	parent string // "" for the root
+	label  string
+	kind   string // "dir", "file" or "more" for omitted entries
+}
+
+// graphNodes lists e and everything below it depth-first, children sorted
+// by name whatever the sort order of e, so that regenerated diagrams
+// differ only where the tree does.
+func graphNodes(e *Entry, opts GraphOptions) []graphNode {
+	var nodes []graphNode
+	var add func(e *Entry, p, parent string)
+	add = func(e *Entry, p, parent string) {
+		n := graphNode{path: p, parent: parent, label: e.Name, kind: "dir"}
+		if e.Type != "dir" {
+			n.label, n.kind = graphLabel(e, opts), "file"
+		}
+		nodes = append(nodes, n)
+		children := append([]*Entry(nil), e.Children...)
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].Name < children[j].Name
+		})
+		for _, c := range children {
+			add(c, path.Join(p, c.Name), p)
+		}
+		if e.Omitted > 0 {
+			label := fmt.Sprintf("… and %d more", e.Omitted)
+			nodes = append(nodes, graphNode{path: path.Join(p, "…"), parent: p, label: label, kind: "more"})
+		}
+	}
+	add(e, ".", "")
+	return nodes
+}
+
+func graphLabel(e *Entry, opts GraphOptions) string {
+	label := e.Name
+	if e.Target != "" {
+		label += " -> " + e.Target
+	}
+	if opts.Sizes && e.Type == "file" {
+		size := "empty"
+		if e.Size > 0 {
+			size = FormatSize(e.Size, opts.Units)
+		}
+		label += "\n" + size
+	}
+	return label
+}
+
+var dotShapes = map[string]string{"dir": "folder", "file": "note", "more": "plaintext"}
+
+// WriteDOT writes e as a Graphviz digraph, directories as folders and
+// everything else as notes. Nodes are identified by their paths.
+func WriteDOT(out io.Writer, e *Entry, opts GraphOptions) error {
+	var b strings.Builder
+	b.WriteString("digraph tree {\n\trankdir=LR;\n\tnode [fontname=\"monospace\"];\n")
+	for _, n := range graphNodes(e, opts) {
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=%s];\n", dotQuote(n.path), dotQuote(n.label), dotShapes[n.kind])
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(n.parent), dotQuote(n.path))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+var mermaidShapes = map[string]string{"dir": "[%s]", "file": "([%s])", "more": ">%s]"}
+
+// WriteMermaid writes e as a Mermaid flowchart, directories as rectangles
+// and everything else as stadiums. Node ids are hashes of the paths, so
+// they stay the same as long as the paths do.
+func WriteMermaid(out io.Writer, e *Entry, opts GraphOptions) error {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, n := range graphNodes(e, opts) {
+		fmt.Fprintf(&b, "\t%s"+mermaidShapes[n.kind]+"\n", mermaidID(n.path), mermaidQuote(n.label))
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s --> %s\n", mermaidID(n.parent), mermaidID(n.path))
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", "<br>")
+
+func mermaidQuote(s string) string {
+	return `"` + mermaidEscaper.Replace(s) + `"`
+}
+
+func mermaidID(p string) string {
+	h := fnv.New64a()
+	io.WriteString(h, p)
+	return fmt.Sprintf("n%016x", h.Sum64())
+}
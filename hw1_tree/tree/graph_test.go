@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+const testDOTResult = `digraph tree {
+	rankdir=LR;
+	node [fontname="monospace"];
+	"." [label="root", shape=folder];
+	"a" [label="a", shape=folder];
+	"." -> "a";
+	"a/b \"c\".txt" [label="b \"c\".txt\n3b", shape=note];
+	"a" -> "a/b \"c\".txt";
+	"a/empty.txt" [label="empty.txt\nempty", shape=note];
+	"a" -> "a/empty.txt";
+	"big.bin" [label="big.bin\n2b", shape=note];
+	"." -> "big.bin";
+}
+`
+
+const testMermaidResult = `graph LR
+	naf63a34c86018bb1["root"]
+	naf63dc4c8601ec8c["a"]
+	naf63a34c86018bb1 --> naf63dc4c8601ec8c
+	nf39015df005c4ea0(["b #quot;c#quot;.txt"])
+	naf63dc4c8601ec8c --> nf39015df005c4ea0
+	n48c33eb9cdfcafcf>"… and 1 more"]
+	naf63dc4c8601ec8c --> n48c33eb9cdfcafcf
+	n5640821b8850f21b>"… and 1 more"]
+	naf63a34c86018bb1 --> n5640821b8850f21b
+`
+
+func TestWriteGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b \"c\".txt": {Data: []byte("abc")},
+		"a/empty.txt":   {},
+		"big.bin":       {Data: []byte("ab")},
+	}
+	// The diagrams are sorted by name whatever the walk order.
+	e, err := CollectFS(fsys, ".", Options{PrintFiles: true, SortBy: SortSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Name = "root"
+	out := new(bytes.Buffer)
+	if err := WriteDOT(out, e, GraphOptions{Sizes: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDOTResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDOTResult)
+	}
+
+	e, err = CollectFS(fsys, ".", Options{PrintFiles: true, MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Name = "root"
+	out.Reset()
+	if err := WriteMermaid(out, e, GraphOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testMermaidResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testMermaidResult)
+	}
+}
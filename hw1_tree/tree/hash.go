@@ -0,0 +1,106 @@
+package tree
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// HashKind selects the content hash of files.
+type HashKind string
+
+const (
+	NoHash HashKind = ""
+	SHA256 HashKind = "sha256"
+	CRC64  HashKind = "crc64" // fast, but not collision resistant
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+func (k HashKind) check() error {
+	switch k {
+	case NoHash, SHA256, CRC64:
+		return nil
+	}
+	return fmt.Errorf("unknown hash %q", string(k))
+}
+
+func (k HashKind) new() hash.Hash {
+	if k == CRC64 {
+		return crc64.New(crcTable)
+	}
+	return sha256.New()
+}
+
+// DuplicateGroup is a set of files with identical content.
+type DuplicateGroup struct {
+	Size  int64
+	Hash  string
+	Paths []string // relative to the root, in print order
+}
+
+// Duplicates groups the files of e with identical content, as found by
+// their size and hash. Entries must have been collected with Options.Hash.
+// Empty files are not reported. Groups are sorted by the space they waste, largest first.
+func Duplicates(e *Entry) []*DuplicateGroup {
+	type key struct {
+		size int64
+		hash string
+	}
+	byContent := map[key]*DuplicateGroup{}
+	var groups []*DuplicateGroup
+	var collect func(e *Entry, dir string)
+	collect = func(e *Entry, dir string) {
+		for _, c := range e.Children {
+			p := path.Join(dir, c.Name)
+			if c.Hash != "" && c.Size > 0 {
+				k := key{c.Size, c.Hash}
+				g, ok := byContent[k]
+				if !ok {
+					g = &DuplicateGroup{Size: c.Size, Hash: c.Hash}
+					byContent[k] = g
+					groups = append(groups, g)
+				}
+				g.Paths = append(g.Paths, p)
+			}
+			collect(c, p)
+		}
+	}
+	collect(e, "")
+
+	dups := groups[:0]
+	for _, g := range groups {
+		if len(g.Paths) > 1 {
+			dups = append(dups, g)
+		}
+	}
+	sort.SliceStable(dups, func(i, j int) bool {
+		return dups[i].wasted() > dups[j].wasted()
+	})
+	return dups
+}
+
+func (g *DuplicateGroup) wasted() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// WriteDuplicates prints the duplicate files of e, one group per paragraph.
+func WriteDuplicates(out io.Writer, e *Entry, units Units) error {
+	var b strings.Builder
+	for i, g := range Duplicates(e) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d copies of %s, %s\n", len(g.Paths), FormatSize(g.Size, units), g.Hash)
+		for _, p := range g.Paths {
+			fmt.Fprintf(&b, "\t%s\n", p)
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
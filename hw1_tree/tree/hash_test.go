@@ -0,0 +1,52 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+)
+
+const testHashResult = `└───lorem
+	├───dolor.txt (empty) crc64:0000000000000000
+	├───gopher.png (70372b) crc64:9be50f14d4c0dcf9
+	└───ipsum
+		└───gopher.png (70372b) crc64:9be50f14d4c0dcf9
+`
+
+const testDuplicatesResult = `7 copies of 70372b, sha256:205b66874721e8feec32a0ca3e4f18506f9c1cd093c97054bdba49d4ee12f803
+	project/gopher.png
+	static/a_lorem/gopher.png
+	static/a_lorem/ipsum/gopher.png
+	static/z_lorem/gopher.png
+	static/z_lorem/ipsum/gopher.png
+	zline/lorem/gopher.png
+	zline/lorem/ipsum/gopher.png
+`
+
+func TestWalkHash(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := Options{PrintFiles: true, Hash: CRC64, Workers: 3, Exclude: []string{"empty.txt"}}
+	if err := Walk("../testdata/zline", opts, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testHashResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testHashResult)
+	}
+
+	if err := Walk("../testdata", Options{Hash: "md4"}, NewTextPrinter(out)); err == nil {
+		t.Errorf("expected error for unknown hash")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	e, err := Collect("../testdata", Options{PrintFiles: true, Hash: SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := WriteDuplicates(out, e, Bytes); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDuplicatesResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDuplicatesResult)
+	}
+}
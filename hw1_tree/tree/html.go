@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"html/template"
+	"io"
+	"net/url"
+	"path"
+)
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: monospace; }
+details { margin-left: 1.5em; }
+ul { list-style: none; margin: 0 0 0 1.5em; padding: 0; }
+nav { margin-left: 1.5em; font-size: smaller; }
+nav a { margin-right: 0.5em; }
+.size { color: #777; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+{{template "dir" .}}
+</body>
+</html>
+{{define "dir"}}<details open id="{{.ID}}">
+<summary><a href="{{.Href}}">{{.Name}}</a>{{if .Target}} -&gt; {{.Target}}{{end}}{{if .Error}} <span class="error">[{{.Error}}]</span>{{end}}</summary>
+{{- if .Subdirs}}
+<nav>{{range .Subdirs}}<a href="{{.Href}}">{{.Name}}/</a>{{end}}</nav>
+{{- end}}
+{{- range .Children}}
+{{if .IsDir}}{{template "dir" .}}{{else}}<ul><li>{{.Name}}{{if .Target}} -&gt; {{.Target}}{{end}} <span class="size">({{.Size}})</span></li></ul>{{end}}
+{{- end}}
+{{- if .Omitted}}
+<ul><li>… and {{.Omitted}} more</li></ul>
+{{- end}}
+</details>{{end}}
+`))
+
+// htmlEntry is an Entry prepared for the HTML template.
+type htmlEntry struct {
+	ID       string // anchor, the path relative to the root
+	Href     template.URL
+	Name     string
+	Target   string
+	Error    string
+	Size     string
+	IsDir    bool
+	Omitted  int
+	Children []*htmlEntry
+	Subdirs  []*htmlEntry
+}
+
+func newHTMLEntry(e *Entry, p string, units Units) *htmlEntry {
+	h := &htmlEntry{
+		ID:      "tree-" + p,
+		Name:    e.Name,
+		Target:  e.Target,
+		Error:   e.Error,
+		IsDir:   e.Type == "dir",
+		Omitted: e.Omitted,
+		Size:    "empty",
+	}
+	h.Href = template.URL("#" + (&url.URL{Fragment: h.ID}).EscapedFragment())
+	if e.Size > 0 {
+		h.Size = FormatSize(e.Size, units)
+	}
+	for _, child := range e.Children {
+		c := newHTMLEntry(child, path.Join(p, child.Name), units)
+		h.Children = append(h.Children, c)
+		if c.IsDir {
+			h.Subdirs = append(h.Subdirs, c)
+		}
+	}
+	return h
+}
+
+// WriteHTML writes e as a self-contained HTML page, with every directory
+// a collapsible <details> element that can be linked to by its path.
+func WriteHTML(out io.Writer, e *Entry, units Units) error {
+	return htmlPage.Execute(out, newHTMLEntry(e, ".", units))
+}
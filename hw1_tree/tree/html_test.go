@@ -0,0 +1,39 @@
+package tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriteHTML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/c.txt":     {Data: []byte("abc")},
+		"a/empty.txt":   {},
+		"<script>.html": {Data: []byte("x")},
+	}
+	e, err := CollectFS(fsys, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := WriteHTML(out, e, Bytes); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	for _, expected := range []string{
+		`<details open id="tree-a/b">`,
+		`<nav><a href="#tree-a/b">b/</a></nav>`,
+		`<li>c.txt <span class="size">(3b)</span></li>`,
+		`<li>empty.txt <span class="size">(empty)</span></li>`,
+		`<li>&lt;script&gt;.html <span class="size">(1b)</span></li>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expected %q in page:\n%v", expected, page)
+		}
+	}
+	if strings.Count(page, "<details") != 3 || strings.Count(page, "<li>") != 3 {
+		t.Errorf("expected 3 directories and 3 files in page:\n%v", page)
+	}
+}
@@ -0,0 +1,177 @@
+package tree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// sniffLen is how much of a file is looked at to tell text from binary,
+// as much as http.DetectContentType considers.
+const sniffLen = 512
+
+var errBinary = errors.New("binary file")
+
+// lineCounter is a Writer counting the lines of what it is given, unless
+// the first sniffLen bytes don't look like text.
+type lineCounter struct {
+	stopBinary bool // fail with errBinary as soon as the content is known to be binary
+
+	head    []byte
+	sniffed bool
+	binary  bool
+	lines   int64
+	last    byte
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if !c.sniffed {
+		n := sniffLen - len(c.head)
+		if n > len(p) {
+			n = len(p)
+		}
+		c.head = append(c.head, p[:n]...)
+		if len(c.head) == sniffLen {
+			c.sniff()
+		}
+	}
+	if c.binary && c.stopBinary {
+		return 0, errBinary
+	}
+	if len(p) > 0 {
+		c.lines += int64(bytes.Count(p, []byte{'\n'}))
+		c.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+func (c *lineCounter) sniff() {
+	c.sniffed = true
+	c.binary = !strings.HasPrefix(http.DetectContentType(c.head), "text/")
+}
+
+// result returns the number of lines, counting a last line without a
+// newline, or whether the content is binary.
+func (c *lineCounter) result() (int64, bool) {
+	if !c.sniffed {
+		c.sniff()
+	}
+	if c.binary {
+		return 0, true
+	}
+	if c.last != '\n' && len(c.head) > 0 {
+		return c.lines + 1, false
+	}
+	return c.lines, false
+}
+
+// sumLines adds up the lines of the files below every directory of items
+// and returns the total.
+func sumLines(items []*item) int64 {
+	var total int64
+	for _, it := range items {
+		if it.info.IsDir() {
+			it.lines = sumLines(it.children)
+		}
+		total += it.lines
+	}
+	return total
+}
+
+// languages maps file extensions to the names in the line count summary.
+// Other extensions are listed as they are.
+var languages = map[string]string{
+	".c":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".go":    "Go",
+	".h":     "C header",
+	".hpp":   "C++ header",
+	".html":  "HTML",
+	".java":  "Java",
+	".js":    "JavaScript",
+	".json":  "JSON",
+	".kt":    "Kotlin",
+	".md":    "Markdown",
+	".proto": "Protocol Buffers",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".toml":  "TOML",
+	".ts":    "TypeScript",
+	".txt":   "Text",
+	".xml":   "XML",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+}
+
+func languageOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if lang, ok := languages[ext]; ok {
+		return lang
+	}
+	if ext == "" {
+		return "(no extension)"
+	}
+	return ext
+}
+
+// LanguageStat is the line count of the text files of one language, as
+// told by their extension.
+type LanguageStat struct {
+	Name  string
+	Files int
+	Lines int64
+}
+
+// addLanguage counts a text file of lines lines in stats.
+func addLanguage(stats []LanguageStat, name string, lines int64) []LanguageStat {
+	lang := languageOf(name)
+	for i := range stats {
+		if stats[i].Name == lang {
+			stats[i].Files++
+			stats[i].Lines += lines
+			return stats
+		}
+	}
+	return append(stats, LanguageStat{Name: lang, Files: 1, Lines: lines})
+}
+
+// sortLanguages sorts stats by lines, most first, then by name.
+func sortLanguages(stats []LanguageStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Lines != stats[j].Lines {
+			return stats[i].Lines > stats[j].Lines
+		}
+		return stats[i].Name < stats[j].Name
+	})
+}
+
+// WriteLanguages writes stats as a table with a final total row.
+func WriteLanguages(out io.Writer, stats []LanguageStat) error {
+	width := len("Language")
+	total := LanguageStat{Name: "Total"}
+	for _, s := range stats {
+		if len(s.Name) > width {
+			width = len(s.Name)
+		}
+		total.Files += s.Files
+		total.Lines += s.Lines
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s %8s %10s\n", width, "Language", "Files", "Lines")
+	for _, s := range append(stats, total) {
+		fmt.Fprintf(&b, "%-*s %8d %10d\n", width, s.Name, s.Files, s.Lines)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testLinesResult = `├───README (1b) 1 line
+├───cmd 3 lines
+│	└───main.go (19b) 3 lines
+├───docs 1 line
+│	├───empty.md (empty) 0 lines
+│	└───intro.md (7b) 1 line
+└───logo.png (600b) binary
+
+2 directories, 5 files, 627 bytes
+
+Language          Files      Lines
+Go                    1          3
+(no extension)        1          1
+Markdown              2          1
+Total                 4          5
+`
+
+func TestWalkLines(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("\n"), 592)...)
+	fsys := fstest.MapFS{
+		"README":         {Data: []byte("x")},
+		"cmd/main.go":    {Data: []byte("package main\n\n// x\n")},
+		"docs/empty.md":  {},
+		"docs/intro.md":  {Data: []byte("# Intro")},
+		"logo.png":       {Data: png},
+		"vendor/lib.txt": {Data: []byte("excluded\n")},
+	}
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.ShowLines = true
+	p.ShowSummary = true
+	opts := Options{PrintFiles: true, CountLines: true, Exclude: []string{"vendor"}}
+	if err := WalkFS(fsys, ".", opts, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testLinesResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testLinesResult)
+	}
+
+	// Directory totals don't depend on the files being printed.
+	out.Reset()
+	opts.PrintFiles = false
+	if err := WalkFS(fsys, ".", opts, p); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "├───cmd 3 lines\n└───docs 1 line\n") {
+		t.Errorf("unexpected directory totals:\n%v", out.String())
+	}
+}
+
+func TestLineCounter(t *testing.T) {
+	cases := []struct {
+		data   string
+		lines  int64
+		binary bool
+	}{
+		{"", 0, false},
+		{"a", 1, false},
+		{"a\n", 1, false},
+		{"a\nb", 2, false},
+		{"\n\n\n", 3, false},
+		{"a\x00b\n", 0, true},
+	}
+	for _, c := range cases {
+		counter := &lineCounter{}
+		counter.Write([]byte(c.data))
+		lines, binary := counter.result()
+		if lines != c.lines || binary != c.binary {
+			t.Errorf("%q: got %d lines, binary %v, want %d, %v", c.data, lines, binary, c.lines, c.binary)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// manifestHeader starts every manifest written by WriteManifest.
+const manifestHeader = "#tree manifest v1"
+
+// WriteManifest writes e as an mtree-like manifest: one line per path
+// with its type, size, permissions, symlink target and content hash.
+// Modification times are left out, so that copies of a tree verify.
+func WriteManifest(out io.Writer, e *Entry) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, manifestHeader)
+	writeManifestEntry(w, e, ".")
+	return w.Flush()
+}
+
+func writeManifestEntry(w *bufio.Writer, e *Entry, p string) {
+	mode, _ := parseModeString(e.Mode)
+	fmt.Fprintf(w, "%s type=%s mode=%04o", escapeManifest(p), manifestType(e), unixPerm(mode))
+	if e.Type != "dir" {
+		fmt.Fprintf(w, " size=%d", e.Size)
+	}
+	if e.Target != "" {
+		fmt.Fprintf(w, " link=%s", escapeManifest(e.Target))
+	}
+	if e.Hash != "" {
+		fmt.Fprintf(w, " %s", strings.Replace(e.Hash, ":", "=", 1))
+	}
+	fmt.Fprintln(w)
+	for _, c := range e.Children {
+		writeManifestEntry(w, c, path.Join(p, c.Name))
+	}
+}
+
+// ReadManifest parses a manifest written by WriteManifest back into
+// entries, ready to be compared with Diff.
+func ReadManifest(r io.Reader) (*Entry, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() || sc.Text() != manifestHeader {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("manifest: missing %q header", manifestHeader)
+	}
+	entries := map[string]*Entry{}
+	line := 1
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p, e, err := parseManifestLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		if _, ok := entries[p]; ok {
+			return nil, fmt.Errorf("manifest line %d: duplicate path %q", line, p)
+		}
+		if p != "." {
+			parent, ok := entries[path.Dir(p)]
+			if !ok || parent.Type != "dir" {
+				return nil, fmt.Errorf("manifest line %d: %q listed before its directory", line, p)
+			}
+			parent.Children = append(parent.Children, e)
+		}
+		entries[p] = e
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	root, ok := entries["."]
+	if !ok {
+		return nil, fmt.Errorf("manifest: no root entry")
+	}
+	return root, nil
+}
+
+func parseManifestLine(text string) (string, *Entry, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("empty line")
+	}
+	p, err := unescapeManifest(fields[0])
+	if err != nil {
+		return "", nil, err
+	}
+	if p != "." && !fs.ValidPath(p) {
+		return "", nil, fmt.Errorf("bad path %q", p)
+	}
+	e := &Entry{Name: path.Base(p)}
+	var mode fs.FileMode
+	for _, field := range fields[1:] {
+		eq := strings.IndexByte(field, '=')
+		if eq < 0 {
+			return "", nil, fmt.Errorf("bad field %q", field)
+		}
+		key, value := field[:eq], field[eq+1:]
+		switch key {
+		case "type":
+			e.Type = value
+		case "mode":
+			perm, err := strconv.ParseUint(value, 8, 32)
+			if err != nil {
+				return "", nil, fmt.Errorf("bad mode %q", value)
+			}
+			mode = fromUnixPerm(uint32(perm))
+		case "size":
+			if e.Size, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return "", nil, fmt.Errorf("bad size %q", value)
+			}
+		case "link":
+			if e.Target, err = unescapeManifest(value); err != nil {
+				return "", nil, err
+			}
+		case string(SHA256), string(CRC64):
+			e.Hash = key + ":" + value
+		default:
+			return "", nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	typ, ok := manifestTypes[e.Type]
+	if !ok {
+		return "", nil, fmt.Errorf("bad type %q", e.Type)
+	}
+	e.Mode = (mode | typ).String()
+	e.Type = fileType(typ)
+	return p, e, nil
+}
+
+// manifestTypes maps the types of a manifest to their mode bits. Entries
+// of type "other" are written as one of the special types when their mode
+// tells which, so that they verify.
+var manifestTypes = map[string]fs.FileMode{
+	"file":    0,
+	"dir":     fs.ModeDir,
+	"symlink": fs.ModeSymlink,
+	"fifo":    fs.ModeNamedPipe,
+	"socket":  fs.ModeSocket,
+	"char":    fs.ModeDevice | fs.ModeCharDevice,
+	"block":   fs.ModeDevice,
+	"other":   fs.ModeIrregular,
+}
+
+// manifestType returns the type of e in a manifest.
+func manifestType(e *Entry) string {
+	if e.Type != "other" {
+		return e.Type
+	}
+	// The type letters of fs.FileMode.String come before the permissions.
+	letters := e.Mode
+	if len(letters) >= 9 {
+		letters = letters[:len(letters)-9]
+	}
+	switch {
+	case strings.ContainsRune(letters, 'p'):
+		return "fifo"
+	case strings.ContainsRune(letters, 'S'):
+		return "socket"
+	case strings.ContainsRune(letters, 'c'):
+		return "char"
+	case strings.ContainsRune(letters, 'D'):
+		return "block"
+	}
+	return e.Type
+}
+
+// HashOf returns the hash kind used in e, or NoHash if nothing is hashed.
+func HashOf(e *Entry) HashKind {
+	if i := strings.IndexByte(e.Hash, ':'); i > 0 {
+		return HashKind(e.Hash[:i])
+	}
+	for _, c := range e.Children {
+		if k := HashOf(c); k != NoHash {
+			return k
+		}
+	}
+	return NoHash
+}
+
+// WriteDeviations lists every difference found by Diff between a manifest
+// and the current tree, one per line, and returns how many there are.
+// Added and removed directories are reported without their contents.
+func WriteDeviations(out io.Writer, d *DiffEntry, opts DiffOptions) (int, error) {
+	w := bufio.NewWriter(out)
+	n := writeDeviations(w, d, ".", opts)
+	return n, w.Flush()
+}
+
+func writeDeviations(w *bufio.Writer, d *DiffEntry, p string, opts DiffOptions) int {
+	n := 0
+	switch d.Status {
+	case Added:
+		fmt.Fprintf(w, "extra    %s\n", p)
+		return 1
+	case Removed:
+		fmt.Fprintf(w, "missing  %s\n", p)
+		return 1
+	case Changed:
+		fmt.Fprintf(w, "changed  %s%s\n", p, d.details(opts))
+		n++
+	}
+	for _, c := range d.Children {
+		if c.changes {
+			n += writeDeviations(w, c, path.Join(p, c.Name), opts)
+		}
+	}
+	return n
+}
+
+// parseModeString turns fs.FileMode.String output back into a mode.
+// Only the permission and setuid, setgid and sticky bits are restored.
+func parseModeString(s string) (fs.FileMode, bool) {
+	if len(s) < 9 {
+		return 0, false
+	}
+	perm := s[len(s)-9:]
+	var mode fs.FileMode
+	for i, c := range perm {
+		if c != '-' {
+			mode |= 1 << uint(8-i)
+		}
+	}
+	for _, c := range s[:len(s)-9] {
+		switch c {
+		case 'u':
+			mode |= fs.ModeSetuid
+		case 'g':
+			mode |= fs.ModeSetgid
+		case 't':
+			mode |= fs.ModeSticky
+		}
+	}
+	return mode, true
+}
+
+// unixPerm returns the permission bits of mode in chmod(1) terms.
+func unixPerm(mode fs.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+func fromUnixPerm(perm uint32) fs.FileMode {
+	mode := fs.FileMode(perm & 0777)
+	if perm&04000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if perm&02000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if perm&01000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
+
+// escapeManifest escapes white space, backslashes and '=' as \ooo, like mtree.
+func escapeManifest(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '\\' || c == '=' || c >= 0x7f {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func unescapeManifest(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+4 > len(s) {
+			return "", fmt.Errorf("bad escape in %q", s)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+4], 8, 8)
+		if err != nil {
+			return "", fmt.Errorf("bad escape in %q", s)
+		}
+		b.WriteByte(byte(c))
+		i += 3
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,126 @@
+package tree
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testManifest = `#tree manifest v1
+. type=dir mode=0755
+bin type=dir mode=0755
+bin/run\040me.sh type=file mode=4755 size=3 sha256=ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+notes.txt type=file mode=0644 size=0 sha256=e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+`
+
+const testDeviationsResult = `changed  bin/run me.sh (content)
+missing  notes.txt
+extra    x
+`
+
+func TestManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		".":             {Mode: fs.ModeDir | 0755},
+		"bin":           {Mode: fs.ModeDir | 0755},
+		"bin/run me.sh": {Data: []byte("abc"), Mode: fs.ModeSetuid | 0755},
+		"notes.txt":     {Mode: 0644},
+	}
+	opts := Options{PrintFiles: true, Hash: SHA256}
+	e, err := CollectFS(fsys, ".", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := WriteManifest(out, e); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testManifest {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testManifest)
+	}
+
+	want, err := ReadManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HashOf(want) != SHA256 {
+		t.Errorf("expected sha256 manifest, got %q", HashOf(want))
+	}
+	diffOpts := DiffOptions{IgnoreTime: true}
+	out.Reset()
+	n, err := WriteDeviations(out, Diff(want, e, diffOpts), diffOpts)
+	if err != nil || n != 0 {
+		t.Errorf("expected no deviations, got %d, %v:\n%v", n, err, out.String())
+	}
+
+	fsys["bin/run me.sh"].Data = []byte("abd")
+	delete(fsys, "notes.txt")
+	fsys["x"] = &fstest.MapFile{}
+	e, err = CollectFS(fsys, ".", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	n, err = WriteDeviations(out, Diff(want, e, diffOpts), diffOpts)
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 deviations, got %d, %v", n, err)
+	}
+	if out.String() != testDeviationsResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDeviationsResult)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	for _, m := range []string{
+		"",
+		"#tree manifest v1\nbin type=dir mode=0755\n",
+		"#tree manifest v1\n. type=dir mode=0755\na/b type=file mode=0644 size=1\n",
+		"#tree manifest v1\n. type=dir mode=0755\na type=pipe mode=0644\n",
+		"#tree manifest v1\n. type=dir mode=0755\na type=file mode=0644 size=x\n",
+		"#tree manifest v1\n. type=dir mode=0755\na\\04 type=file mode=0644\n",
+	} {
+		if _, err := ReadManifest(strings.NewReader(m)); err == nil {
+			t.Errorf("expected error for manifest %q", m)
+		}
+	}
+}
+
+const testManifestSpecial = `#tree manifest v1
+. type=dir mode=0755
+dev type=char mode=0600 size=0
+pipe type=fifo mode=0644 size=0
+sock type=socket mode=0755 size=0
+`
+
+func TestManifestSpecialFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		".":    {Mode: fs.ModeDir | 0755},
+		"dev":  {Mode: fs.ModeDevice | fs.ModeCharDevice | 0600},
+		"pipe": {Mode: fs.ModeNamedPipe | 0644},
+		"sock": {Mode: fs.ModeSocket | 0755},
+	}
+	e, err := CollectFS(fsys, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := WriteManifest(out, e); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testManifestSpecial {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testManifestSpecial)
+	}
+
+	// Blank lines, even with spaces, are skipped.
+	want, err := ReadManifest(strings.NewReader(testManifestSpecial + "  \t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffOpts := DiffOptions{IgnoreTime: true}
+	out.Reset()
+	n, err := WriteDeviations(out, Diff(want, e, diffOpts), diffOpts)
+	if err != nil || n != 0 {
+		t.Errorf("expected no deviations, got %d, %v:\n%v", n, err, out.String())
+	}
+}
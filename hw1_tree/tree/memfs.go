@@ -0,0 +1,249 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// MemFS is a read-only in-memory file system, filled with Add.
+// It is used for archives that can't be read in place. Symlinks are
+// stored with their target but aren't resolved by Open.
+type MemFS struct {
+	files map[string]*memFile
+	lazy  int // files added with AddLazy
+}
+
+type memFile struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	size     int64 // reads past data return zeros up to size
+	children map[string]*memFile
+
+	open  func() (io.ReadCloser, error) // contents past data, for files added with AddLazy
+	order int                           // of the calls to AddLazy
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	root := &memFile{name: ".", mode: fs.ModeDir | 0755, children: map[string]*memFile{}}
+	return &MemFS{files: map[string]*memFile{".": root}}
+}
+
+// Add creates name with the given mode and contents, creating missing
+// parent directories. For symlinks data is the link target. Adding an
+// existing directory again updates its mode and mtime.
+func (m *MemFS) Add(name string, mode fs.FileMode, modTime time.Time, data []byte) error {
+	return m.add(name, mode, modTime, data, int64(len(data)))
+}
+
+// AddZero creates a regular file of the given size, reading as zeros,
+// without allocating its contents.
+func (m *MemFS) AddZero(name string, perm fs.FileMode, modTime time.Time, size int64) error {
+	return m.add(name, perm.Perm(), modTime, nil, size)
+}
+
+// AddLazy creates a regular file of the given size starting with head,
+// whose contents are read from open, from the start, once a handle reads
+// past head. Such files are best read in the order they were added, see
+// readOrder.
+func (m *MemFS) AddLazy(name string, perm fs.FileMode, modTime time.Time, head []byte, size int64, open func() (io.ReadCloser, error)) error {
+	if err := m.add(name, perm.Perm(), modTime, head, size); err != nil {
+		return err
+	}
+	f := m.files[name]
+	f.open, f.order = open, m.lazy
+	m.lazy++
+	return nil
+}
+
+// readOrder implements orderedFS: files added with AddLazy come in the
+// order they were added, after the others.
+func (m *MemFS) readOrder(name string) (int, bool) {
+	f, ok := m.files[name]
+	if !ok || f.open == nil {
+		return 0, false
+	}
+	return f.order, true
+}
+
+func (m *MemFS) add(name string, mode fs.FileMode, modTime time.Time, data []byte, size int64) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "add", Path: name, Err: fs.ErrInvalid}
+	}
+	parent, err := m.mkdirAll(path.Dir(name), modTime)
+	if err != nil {
+		return err
+	}
+	if f, ok := m.files[name]; ok {
+		if !f.mode.IsDir() || !mode.IsDir() {
+			return &fs.PathError{Op: "add", Path: name, Err: fs.ErrExist}
+		}
+		f.mode, f.modTime = mode, modTime
+		return nil
+	}
+	f := &memFile{name: path.Base(name), mode: mode, modTime: modTime, data: data, size: size}
+	if mode.IsDir() {
+		f.children = map[string]*memFile{}
+	}
+	m.files[name] = f
+	parent.children[f.name] = f
+	return nil
+}
+
+// removeFile removes name unless it is a directory.
+func (m *MemFS) removeFile(name string) {
+	f, ok := m.files[name]
+	if !ok || f.mode.IsDir() {
+		return
+	}
+	delete(m.files, name)
+	delete(m.files[path.Dir(name)].children, f.name)
+}
+
+func (m *MemFS) mkdirAll(dir string, modTime time.Time) (*memFile, error) {
+	if f, ok := m.files[dir]; ok {
+		if !f.mode.IsDir() {
+			return nil, &fs.PathError{Op: "add", Path: dir, Err: ErrNotDir}
+		}
+		return f, nil
+	}
+	parent, err := m.mkdirAll(path.Dir(dir), modTime)
+	if err != nil {
+		return nil, err
+	}
+	f := &memFile{name: path.Base(dir), mode: fs.ModeDir | 0755, modTime: modTime, children: map[string]*memFile{}}
+	m.files[dir] = f
+	parent.children[f.name] = f
+	return f, nil
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memHandle{f: f}, nil
+}
+
+// ReadLink returns the target of the symlink name.
+func (m *MemFS) ReadLink(name string) (string, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if f.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return string(f.data), nil
+}
+
+func (f *memFile) Name() string       { return f.name }
+func (f *memFile) Size() int64        { return f.size }
+func (f *memFile) Mode() fs.FileMode  { return f.mode }
+func (f *memFile) ModTime() time.Time { return f.modTime }
+func (f *memFile) IsDir() bool        { return f.mode.IsDir() }
+func (f *memFile) Sys() interface{}   { return nil }
+
+// memDirEntry adapts a memFile to fs.DirEntry.
+type memDirEntry struct{ f *memFile }
+
+func (e memDirEntry) Name() string               { return e.f.name }
+func (e memDirEntry) IsDir() bool                { return e.f.IsDir() }
+func (e memDirEntry) Type() fs.FileMode          { return e.f.mode.Type() }
+func (e memDirEntry) Info() (fs.FileInfo, error) { return e.f, nil }
+
+// memHandle is an open memFile.
+type memHandle struct {
+	f       *memFile
+	offset  int64
+	entries []fs.DirEntry // remaining directory entries, after the first ReadDir
+	listed  bool
+	r       io.ReadCloser // contents of a file added with AddLazy, once read
+}
+
+func (h *memHandle) Stat() (fs.FileInfo, error) { return h.f, nil }
+
+func (h *memHandle) Close() error {
+	if h.r != nil {
+		return h.r.Close()
+	}
+	return nil
+}
+
+func (h *memHandle) Read(b []byte) (int, error) {
+	if h.f.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: h.f.name, Err: errors.New("is a directory")}
+	}
+	if h.f.open != nil && h.offset >= int64(len(h.f.data)) && h.offset < h.f.size {
+		if h.r == nil {
+			r, err := h.f.open()
+			if err != nil {
+				return 0, err
+			}
+			if _, err := io.CopyN(io.Discard, r, h.offset); err != nil {
+				r.Close()
+				return 0, err
+			}
+			h.r = r
+		}
+		n, err := h.r.Read(b)
+		h.offset += int64(n)
+		return n, err
+	}
+	if h.offset >= h.f.size {
+		return 0, io.EOF
+	}
+	if rest := h.f.size - h.offset; int64(len(b)) > rest {
+		b = b[:rest]
+	}
+	if rest := int64(len(h.f.data)) - h.offset; h.f.open != nil && int64(len(b)) > rest {
+		b = b[:rest]
+	}
+	n := 0
+	if h.offset < int64(len(h.f.data)) {
+		n = copy(b, h.f.data[h.offset:])
+	}
+	for i := n; i < len(b); i++ {
+		b[i] = 0
+	}
+	h.offset += int64(len(b))
+	return len(b), nil
+}
+
+func (h *memHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !h.f.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: h.f.name, Err: ErrNotDir}
+	}
+	if !h.listed {
+		for _, child := range h.f.children {
+			h.entries = append(h.entries, memDirEntry{child})
+		}
+		sort.Slice(h.entries, func(i, j int) bool {
+			return h.entries[i].Name() < h.entries[j].Name()
+		})
+		h.listed = true
+	}
+	if n <= 0 {
+		entries := h.entries
+		h.entries = nil
+		return entries, nil
+	}
+	if len(h.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(h.entries) {
+		n = len(h.entries)
+	}
+	entries := h.entries[:n]
+	h.entries = h.entries[n:]
+	return entries, nil
+}
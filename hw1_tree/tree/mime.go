@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sniffMIME returns the media type of the file at p, without parameters,
+// as told by http.DetectContentType from its first bytes.
+func (w *walker) sniffMIME(p string) (string, error) {
+	f, err := w.fsys.Open(w.fsPath(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return detectMIME(head[:n]), nil
+}
+
+// detectMIME returns the media type of content starting with head,
+// without parameters.
+func detectMIME(head []byte) string {
+	mime := http.DetectContentType(head)
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	return mime
+}
+
+// headWriter is a Writer keeping the first sniffLen bytes of what it is
+// given.
+type headWriter struct {
+	buf []byte
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if n := sniffLen - len(h.buf); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		h.buf = append(h.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+func checkMIMEPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("bad MIME pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// matchMIME matches a glob such as "image/*" against a media type. A
+// pattern without a slash, such as "image", matches the whole class.
+func matchMIME(pattern, mime string) bool {
+	if !strings.Contains(pattern, "/") {
+		pattern += "/*"
+	}
+	ok, _ := path.Match(pattern, mime)
+	return ok
+}
@@ -0,0 +1,72 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+const testMIMEResult = `├───assets
+│	├───logo.png [image/png] (8b)
+│	└───notes.txt [image/png] (8b)
+├───index.html [text/html] (14b)
+└───readme.txt [text/plain] (5b)
+`
+
+const testMIMEFilterResult = `└───assets
+	└───notes.txt [image/png] (8b)
+`
+
+func TestWalkMIME(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	fsys := fstest.MapFS{
+		"assets/logo.png":  {Data: png},
+		"assets/notes.txt": {Data: png},
+		"docs/manual.pdf":  {Data: []byte("%PDF-1.4")},
+		"index.html":       {Data: []byte("<html><body>..")},
+		"readme.txt":       {Data: []byte("hello")},
+	}
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.ShowMIME = true
+	opts := Options{PrintFiles: true, SniffMIME: true, ExcludeMIME: []string{"application/pdf"}}
+	if err := WalkFS(fsys, ".", opts, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testMIMEResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testMIMEResult)
+	}
+
+	// Text files whose content is an image.
+	out.Reset()
+	opts = Options{PrintFiles: true, Include: []string{"*.txt"}, IncludeMIME: []string{"image"}}
+	if err := WalkFS(fsys, ".", opts, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testMIMEFilterResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testMIMEFilterResult)
+	}
+
+	if err := WalkFS(fsys, ".", Options{IncludeMIME: []string{"image/["}}, p); err == nil {
+		t.Errorf("expected error for bad pattern")
+	}
+}
+
+func TestMatchMIME(t *testing.T) {
+	cases := []struct {
+		pattern, mime string
+		expected      bool
+	}{
+		{"image", "image/png", true},
+		{"image/*", "image/png", true},
+		{"image/png", "image/png", true},
+		{"image/png", "image/gif", false},
+		{"text", "image/png", false},
+		{"*/xml", "text/xml", true},
+	}
+	for _, c := range cases {
+		if got := matchMIME(c.pattern, c.mime); got != c.expected {
+			t.Errorf("matchMIME(%q, %q) = %v, want %v", c.pattern, c.mime, got, c.expected)
+		}
+	}
+}
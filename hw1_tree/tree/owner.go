@@ -0,0 +1,43 @@
+package tree
+
+import (
+	"os/user"
+	"strconv"
+)
+
+// idNames caches user and group names looked up by id. Unknown ids are
+// shown as numbers.
+type idNames struct {
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+func newIDNames() *idNames {
+	return &idNames{users: map[uint32]string{}, groups: map[uint32]string{}}
+}
+
+func (c *idNames) user(uid uint32) string {
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	id := strconv.FormatUint(uint64(uid), 10)
+	name := id
+	if u, err := user.LookupId(id); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+func (c *idNames) group(gid uint32) string {
+	if name, ok := c.groups[gid]; ok {
+		return name
+	}
+	id := strconv.FormatUint(uint64(gid), 10)
+	name := id
+	if g, err := user.LookupGroupId(id); err == nil {
+		name = g.Name
+	}
+	c.groups[gid] = name
+	return name
+}
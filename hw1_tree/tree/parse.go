@@ -0,0 +1,140 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseText reads a tree in the format printed by TextPrinter with default
+// settings and rebuilds it as a MemFS. Entries with a "(Nb)" or "(empty)"
+// suffix become files of that size reading as zeros, all others become
+// directories. Parsing stops at the first empty line, so a summary
+// printed with ShowSummary is ignored.
+func ParseText(r io.Reader) (*MemFS, error) {
+	m := NewMemFS()
+	dirs := []string{"."} // dirs[d] is the directory holding entries at depth d
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if text == "" {
+			break
+		}
+		depth, rest, err := parseBranch(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if depth >= len(dirs) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line)
+		}
+		dirs = dirs[:depth+1]
+
+		name, size, isFile := parseLabel(rest)
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("line %d: bad name %q", line, name)
+		}
+		p := path.Join(dirs[depth], name)
+		if seen[p] {
+			return nil, fmt.Errorf("line %d: duplicate entry %q", line, p)
+		}
+		seen[p] = true
+		if isFile {
+			err = m.AddZero(p, 0644, time.Time{}, size)
+		} else {
+			err = m.Add(p, fs.ModeDir|0755, time.Time{}, nil)
+			dirs = append(dirs, p)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseBranch splits a line into the depth of its entry and the label
+// after the "├───" or "└───" glyph.
+func parseBranch(text string) (int, string, error) {
+	depth := 0
+	for {
+		switch {
+		case strings.HasPrefix(text, "│\t"):
+			text = text[len("│\t"):]
+		case strings.HasPrefix(text, "\t"):
+			text = text[1:]
+		case strings.HasPrefix(text, "├───"):
+			return depth, text[len("├───"):], nil
+		case strings.HasPrefix(text, "└───"):
+			return depth, text[len("└───"):], nil
+		default:
+			return 0, "", fmt.Errorf("no branch glyph in %q", text)
+		}
+		depth++
+	}
+}
+
+// parseLabel splits "name (70372b)" or "name (empty)" into the name and
+// size of a file; a label without a size is a directory.
+func parseLabel(label string) (name string, size int64, isFile bool) {
+	if strings.HasSuffix(label, " (empty)") {
+		return strings.TrimSuffix(label, " (empty)"), 0, true
+	}
+	if strings.HasSuffix(label, "b)") {
+		if i := strings.LastIndex(label, " ("); i >= 0 {
+			n, err := strconv.ParseInt(label[i+2:len(label)-2], 10, 64)
+			if err == nil && n >= 0 {
+				return label[:i], n, true
+			}
+		}
+	}
+	return label, 0, false
+}
+
+// WriteFS copies every file and directory of fsys under the OS directory
+// dir, creating it if needed. Existing files are never overwritten.
+func WriteFS(dir string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(target, fsys, p, info.Mode().Perm())
+	})
+}
+
+func copyFile(target string, fsys fs.FS, name string, perm fs.FileMode) error {
+	in, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseTextRoundTrip(t *testing.T) {
+	text := new(bytes.Buffer)
+	if err := Walk("../testdata", Options{PrintFiles: true}, NewTextPrinter(text)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseText(bytes.NewReader(text.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(m, "project/gopher.png", "zline/lorem/ipsum/gopher.png", "zzfile.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(m, "project/file.txt")
+	if err != nil || !bytes.Equal(data, make([]byte, 19)) {
+		t.Errorf("expected 19 zero bytes, got %q, %v", data, err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := WalkFS(m, ".", Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != text.String() {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), text.String())
+	}
+
+	dir := t.TempDir()
+	if err := WriteFS(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := Walk(dir, Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != text.String() {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), text.String())
+	}
+	if err := WriteFS(dir, m); err == nil {
+		t.Errorf("expected error when overwriting files")
+	}
+}
+
+func TestParseTextErrors(t *testing.T) {
+	for _, text := range []string{
+		"project\n",
+		"├───a (empty)\n│\t└───b\n",
+		"└───a\n\t\t└───b\n",
+		"├───a/b\n",
+		"├───a\n└───a\n",
+	} {
+		if _, err := ParseText(strings.NewReader(text)); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
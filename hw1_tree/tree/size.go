@@ -0,0 +1,74 @@
+package tree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Units select how sizes are formatted.
+type Units int
+
+const (
+	Bytes Units = iota // 70372b
+	IEC                // 68.7KiB, powers of 1024
+	SI                 // 70.4kB, powers of 1000
+)
+
+// FormatSize formats n bytes in the given units.
+func FormatSize(n int64, u Units) string {
+	var base float64
+	var suffixes []string
+	switch u {
+	case IEC:
+		base, suffixes = 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	case SI:
+		base, suffixes = 1000, []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	default:
+		return fmt.Sprintf("%db", n)
+	}
+	if float64(n) < base {
+		return fmt.Sprintf("%d%s", n, suffixes[0])
+	}
+	v := float64(n)
+	i := 0
+	for v >= base && i < len(suffixes)-1 {
+		v /= base
+		i++
+	}
+	return fmt.Sprintf("%.1f%s", v, suffixes[i])
+}
+
+// ParseSize parses sizes such as "512", "10M", "10MiB" or "1.5GB". Suffixes
+// ending in B without i are powers of 1000, the others powers of 1024.
+// Suffixes are case-insensitive.
+func ParseSize(s string) (int64, error) {
+	upper := strings.ToUpper(s)
+	num := strings.TrimRight(upper, "KMGTPEIB")
+	suffix := upper[len(num):]
+	base := 1024.0
+	switch {
+	case suffix == "B":
+		suffix = ""
+	case strings.HasSuffix(suffix, "IB"):
+		if suffix = strings.TrimSuffix(suffix, "IB"); suffix == "" {
+			return 0, fmt.Errorf("bad size %q", s)
+		}
+	case strings.HasSuffix(suffix, "B"):
+		suffix, base = strings.TrimSuffix(suffix, "B"), 1000
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 || len(suffix) > 1 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	if suffix != "" {
+		i := strings.Index("KMGTPE", suffix)
+		if i < 0 {
+			return 0, fmt.Errorf("bad size %q", s)
+		}
+		for ; i >= 0; i-- {
+			v *= base
+		}
+	}
+	return int64(v), nil
+}
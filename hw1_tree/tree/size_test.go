@@ -0,0 +1,101 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	cases := []struct {
+		n        int64
+		u        Units
+		expected string
+	}{
+		{70372, Bytes, "70372b"},
+		{0, IEC, "0B"},
+		{1023, IEC, "1023B"},
+		{70372, IEC, "68.7KiB"},
+		{3 << 20, IEC, "3.0MiB"},
+		{999, SI, "999B"},
+		{70372, SI, "70.4kB"},
+		{1500000000, SI, "1.5GB"},
+	}
+	for _, c := range cases {
+		if got := FormatSize(c.n, c.u); got != c.expected {
+			t.Errorf("FormatSize(%d, %d) = %q, want %q", c.n, c.u, got, c.expected)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512b", 512},
+		{"10k", 10 << 10},
+		{"10M", 10 << 20},
+		{"10MiB", 10 << 20},
+		{"10MB", 10000000},
+		{"1.5GB", 1500000000},
+		{"2kB", 2000},
+		{"10kb", 10000},
+		{"10m", 10 << 20},
+		{"10mb", 10000000},
+		{"1gib", 1 << 30},
+		{"1.5Gb", 1500000000},
+	}
+	for _, c := range cases {
+		got, err := ParseSize(c.s)
+		if err != nil || got != c.expected {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", c.s, got, err, c.expected)
+		}
+	}
+	for _, s := range []string{"", "M", "-1", "10X", "10MM", "1iB", "ten"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q): expected error", s)
+		}
+	}
+}
+
+const testDirSizesResult = `└───lorem (140744b)
+	└───ipsum (70372b)
+
+2 directories, 4 files, 140744 bytes
+`
+
+func TestWalkDirSizes(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.DirSizes = true
+	p.ShowSummary = true
+	if err := Walk("../testdata/zline", Options{}, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDirSizesResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDirSizesResult)
+	}
+}
+
+const testDirSizesDepthResult = `├───project (70391b)
+├───static (281583b)
+└───zline (140744b)
+
+3 directories, 1 files, 492718 bytes
+`
+
+func TestWalkDirSizesDepth(t *testing.T) {
+	// Directories at the depth limit are still totalled.
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.DirSizes = true
+	p.ShowSummary = true
+	if err := Walk("../testdata", Options{MaxDepth: 1}, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testDirSizesDepthResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testDirSizesDepthResult)
+	}
+}
@@ -0,0 +1,120 @@
+package tree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SortKey selects the order of entries within a directory.
+type SortKey string
+
+const (
+	SortName    SortKey = ""        // byte order of names
+	SortNatural SortKey = "natural" // case-insensitive, numbers by value: file2 before file10
+	SortSize    SortKey = "size"    // largest first, directories by total size
+	SortTime    SortKey = "mtime"   // newest first
+	SortExt     SortKey = "ext"     // by extension, then by name
+)
+
+func (k SortKey) check() error {
+	switch k {
+	case SortName, "name", SortNatural, SortSize, SortTime, SortExt:
+		return nil
+	}
+	return fmt.Errorf("unknown sort key %q", string(k))
+}
+
+// sortItems orders items by the sort options. Ties are broken by name,
+// so the order doesn't depend on the order items were read in.
+func (w *walker) sortItems(items []*item) {
+	sort.Slice(items, func(i, j int) bool {
+		return w.before(items[i], items[j])
+	})
+}
+
+// before reports whether a is listed before b.
+func (w *walker) before(a, b *item) bool {
+	if w.opts.DirsFirst && a.info.IsDir() != b.info.IsDir() {
+		return a.info.IsDir()
+	}
+	c := w.compare(a, b)
+	if c == 0 {
+		c = strings.Compare(a.info.Name(), b.info.Name())
+	}
+	if w.opts.Reverse {
+		return c > 0
+	}
+	return c < 0
+}
+
+// compare orders a and b by the sort key alone.
+func (w *walker) compare(a, b *item) int {
+	switch w.opts.SortBy {
+	case SortNatural:
+		switch {
+		case naturalLess(a.info.Name(), b.info.Name()):
+			return -1
+		case naturalLess(b.info.Name(), a.info.Name()):
+			return 1
+		}
+	case SortSize:
+		switch {
+		case a.size > b.size:
+			return -1
+		case a.size < b.size:
+			return 1
+		}
+	case SortTime:
+		switch {
+		case a.info.ModTime().After(b.info.ModTime()):
+			return -1
+		case a.info.ModTime().Before(b.info.ModTime()):
+			return 1
+		}
+	case SortExt:
+		return strings.Compare(path.Ext(a.info.Name()), path.Ext(b.info.Name()))
+	}
+	return 0
+}
+
+// naturalLess compares names case-insensitively, with runs of digits
+// compared by value. Names equal that way fall back to byte order.
+func naturalLess(a, b string) bool {
+	x, y := strings.ToLower(a), strings.ToLower(b)
+	for x != "" && y != "" {
+		if isDigit(x[0]) && isDigit(y[0]) {
+			nx, ny := digitRun(x), digitRun(y)
+			vx, vy := strings.TrimLeft(x[:nx], "0"), strings.TrimLeft(y[:ny], "0")
+			if len(vx) != len(vy) {
+				return len(vx) < len(vy)
+			}
+			if vx != vy {
+				return vx < vy
+			}
+			x, y = x[nx:], y[ny:]
+			continue
+		}
+		if x[0] != y[0] {
+			return x[0] < y[0]
+		}
+		x, y = x[1:], y[1:]
+	}
+	if len(x) != len(y) {
+		return len(x) < len(y)
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNaturalLess(t *testing.T) {
+	cases := []struct {
+		a, b string
+		less bool
+	}{
+		{"file2", "file10", true},
+		{"file10", "file2", false},
+		{"a", "Z", true},
+		{"v1.2.10", "v1.10.1", true},
+		{"file02", "file2", true},
+		{"file", "file1", true},
+	}
+	for _, c := range cases {
+		if got := naturalLess(c.a, c.b); got != c.less {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", c.a, c.b, got, c.less)
+		}
+	}
+}
+
+func TestWalkSort(t *testing.T) {
+	now := time.Date(2021, 9, 8, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"file10.txt": {Data: []byte("1234567890"), ModTime: now},
+		"file2.go":   {Data: []byte("12"), ModTime: now.Add(time.Hour)},
+		"Zeta.md":    {Data: []byte("12345"), ModTime: now.Add(-time.Hour)},
+		"dir/a.txt":  {Data: []byte("1"), ModTime: now},
+	}
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, "Zeta.md dir file10.txt file2.go "},
+		{Options{SortBy: SortNatural}, "dir file2.go file10.txt Zeta.md "},
+		{Options{SortBy: SortSize}, "file10.txt Zeta.md file2.go dir "},
+		{Options{SortBy: SortTime, DirsFirst: true}, "dir file2.go file10.txt Zeta.md "},
+		{Options{SortBy: SortExt}, "dir file2.go Zeta.md file10.txt "},
+		{Options{Reverse: true, DirsFirst: true}, "dir file2.go file10.txt Zeta.md "},
+	}
+	for _, c := range cases {
+		c.opts.PrintFiles = true
+		c.opts.MaxDepth = 1
+		out := new(bytes.Buffer)
+		err := WalkFS(fsys, ".", c.opts, VisitorFunc(func(n Node) error {
+			out.WriteString(n.Info.Name() + " ")
+			return nil
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.expected {
+			t.Errorf("sort %+v: got %q, want %q", c.opts, out.String(), c.expected)
+		}
+	}
+
+	if err := WalkFS(fsys, ".", Options{SortBy: "color"}, NewTextPrinter(new(bytes.Buffer))); err == nil {
+		t.Errorf("expected error for unknown sort key")
+	}
+}
@@ -0,0 +1,152 @@
+package tree
+
+import "io/fs"
+
+// listing is a directory read by the streaming walker: the entries to be
+// visited, in print order, and the totals of all the entries kept.
+type listing struct {
+	items   []*item
+	omitted int // entries left out by MaxEntries
+	stats   Stats
+	ignore  []ignoreRule // rules for the entries below the directory
+	err     error
+}
+
+// list reads dir for walkStreamed. Entries are filtered batch by batch as
+// they are read and, with MaxEntries, only the first ones in print order
+// are kept, so that listing a huge directory takes memory for the entries
+// visited only. Once the walk is cancelled it returns the entries read so
+// far with the error of the context.
+func (w *walker) list(dir string, depth int, ignore []ignoreRule, ancestors []fileID) *listing {
+	if w.opts.GitIgnore {
+		ignore = loadIgnore(w.fsys, w.fsPath(dir), dir, ignore)
+	}
+	l := &listing{ignore: ignore}
+	max := w.opts.MaxEntries
+	listed := 0
+	l.err = w.readDirFunc(dir, func(info fs.FileInfo) {
+		it := w.newItem(dir, depth, info, ignore, ancestors)
+		if it == nil {
+			return
+		}
+		if it.info.IsDir() {
+			l.stats.Dirs++
+		} else {
+			l.stats.Files++
+			l.stats.Bytes += it.size
+		}
+		if !w.listed(it) {
+			return
+		}
+		listed++
+		l.items = append(l.items, it)
+		if max > 0 && len(l.items) == 2*max {
+			l.items = w.first(l.items, max)
+		}
+	})
+	if l.err != nil && w.ctx.Err() == nil {
+		return &listing{err: l.err}
+	}
+	if max > 0 && len(l.items) > max {
+		l.items = w.first(l.items, max)
+	} else {
+		w.sortItems(l.items)
+	}
+	l.omitted = listed - len(l.items)
+	return l
+}
+
+// first returns the first n items in print order, clearing the others
+// so that they can be freed.
+func (w *walker) first(items []*item, n int) []*item {
+	w.sortItems(items)
+	for i := n; i < len(items); i++ {
+		items[i] = nil
+	}
+	return items[:n]
+}
+
+// walkStreamed visits the tree as it is read, one directory at a time,
+// so that only the directories on the path to the current entry are held.
+func (w *walker) walkStreamed(name string, ancestors []fileID) error {
+	l := w.list("", 0, nil, ancestors)
+	if l.err != nil && w.ctx.Err() == nil {
+		return &RootError{Path: name, Err: l.err}
+	}
+	return w.stream("", 0, l, ancestors)
+}
+
+// stream visits the entries of l, the listing of dir, reading every
+// subdirectory just before it is visited. Spare workers read the next
+// subdirectories ahead, as many as there are workers, so a busy pool never
+// blocks the walk.
+func (w *walker) stream(dir string, depth int, l *listing, ancestors []fileID) error {
+	w.stats.Dirs += l.stats.Dirs
+	w.stats.Files += l.stats.Files
+	w.stats.Bytes += l.stats.Bytes
+	for _, it := range l.items {
+		it.visited = true
+	}
+	if err := w.readFiles(w.contentItems(l.items, nil)); err != nil {
+		return err
+	}
+
+	pending := make([]chan *listing, len(l.items))
+	ahead := 0
+	prefetch := func(i int) {
+		for ; ahead < len(l.items) && ahead <= i+cap(w.workers); ahead++ {
+			it := l.items[ahead]
+			if !it.read {
+				continue
+			}
+			select {
+			case w.workers <- struct{}{}:
+			default:
+				return
+			}
+			ch := make(chan *listing, 1)
+			pending[ahead] = ch
+			go func(it *item) {
+				ch <- w.list(it.path, depth+1, l.ignore, w.childAncestors(it, ancestors))
+				<-w.workers
+			}(it)
+		}
+	}
+
+	for i, it := range l.items {
+		if ahead <= i {
+			ahead = i + 1
+		}
+		prefetch(i)
+		var sub *listing
+		if it.read {
+			if pending[i] != nil {
+				sub = <-pending[i]
+			} else {
+				sub = w.list(it.path, depth+1, l.ignore, w.childAncestors(it, ancestors))
+			}
+			if sub.err != nil {
+				if !w.opts.KeepGoing && w.ctx.Err() == nil {
+					return sub.err
+				}
+				it.err = sub.err
+			}
+		}
+		if it.err != nil && it.err != w.ctx.Err() {
+			w.errs = append(w.errs, it.err)
+		}
+		if err := w.v.Visit(w.node(it, depth, i == len(l.items)-1 && l.omitted == 0)); err != nil {
+			return err
+		}
+		if sub != nil {
+			if err := w.stream(it.path, depth+1, sub, w.childAncestors(it, ancestors)); err != nil {
+				return err
+			}
+		}
+		l.items[i] = nil
+	}
+	if l.omitted > 0 {
+		return w.v.Visit(Node{Path: dir, Depth: depth, IsLast: true, Omitted: l.omitted})
+	}
+	return nil
+}
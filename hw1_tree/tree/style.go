@@ -0,0 +1,65 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Style is the set of strings a tree is drawn with.
+type Style struct {
+	Branch string // in front of an entry with more entries after it
+	Last   string // in front of the last entry of a directory
+	Pipe   string // indentation below a Branch
+	Blank  string // indentation below a Last
+}
+
+// DefaultStyle is the box-drawing style with tab indentation that
+// the tree utility has always printed.
+var DefaultStyle = Style{
+	Branch: "├───",
+	Last:   "└───",
+	Pipe:   "│\t",
+	Blank:  "\t",
+}
+
+// NewStyle returns the style for a charset, "unicode" or "ascii", indented
+// by indent spaces per level, or by tabs if indent is 0.
+func NewStyle(charset string, indent int) (Style, error) {
+	var s Style
+	var pipe string
+	switch charset {
+	case "unicode", "":
+		s, pipe = DefaultStyle, "│"
+	case "ascii":
+		s, pipe = Style{Branch: "|-- ", Last: "`-- ", Pipe: "|\t", Blank: "\t"}, "|"
+	default:
+		return Style{}, fmt.Errorf("unknown charset %q", charset)
+	}
+	if indent < 0 {
+		return Style{}, fmt.Errorf("bad indent %d", indent)
+	}
+	if indent > 0 {
+		s.Pipe = pipe + strings.Repeat(" ", indent-1)
+		s.Blank = strings.Repeat(" ", indent)
+	}
+	return s, nil
+}
+
+// prefix returns the indentation and glyph in front of an entry whose
+// ancestors have the given IsLast flags.
+func (s Style) prefix(ancestors []bool, isLast bool) string {
+	var b strings.Builder
+	for _, last := range ancestors {
+		if last {
+			b.WriteString(s.Blank)
+		} else {
+			b.WriteString(s.Pipe)
+		}
+	}
+	if isLast {
+		b.WriteString(s.Last)
+	} else {
+		b.WriteString(s.Branch)
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// TextPrinter is a Visitor that draws the tree with box-drawing glyphs.
+type TextPrinter struct {
+	Units       Units // how sizes are formatted
+	DirSizes    bool  // print the total size next to directories
+	ShowSummary bool  // end with a line of totals, as tree does
+	ShowLines   bool  // print line counts and end with a table of them, with Options.CountLines
+	ShowMIME    bool  // print media types next to names, with Options.SniffMIME
+
+	// Metadata columns, printed in brackets in front of the name, like tree -pugD.
+	ShowMode   bool
+	ShowOwner  bool
+	ShowGroup  bool
+	ShowTime   bool
+	TimeFormat string // time.Format layout for ShowTime, "Jan _2 15:04" if empty
+	ShowInode  bool
+
+	Colors *Colors // color names with ANSI escapes; nil for plain text
+	Style  Style   // glyphs and indentation, DefaultStyle by NewTextPrinter
+
+	// Highlight holds the paths whose names are printed in reverse video,
+	// such as the changes reported by Watch.
+	Highlight map[string]bool
+
+	out   io.Writer
+	last  []bool // IsLast of every ancestor of the current node
+	names *idNames
+}
+
+// NewTextPrinter returns a TextPrinter writing to out.
+func NewTextPrinter(out io.Writer) *TextPrinter {
+	return &TextPrinter{out: out, Style: DefaultStyle, names: newIDNames()}
+}
+
+func (p *TextPrinter) Visit(n Node) error {
+	p.last = append(p.last[:n.Depth], n.IsLast)
+
+	prefix := p.Style.prefix(p.last[:n.Depth], n.IsLast)
+
+	if n.Info == nil {
+		_, err := fmt.Fprintf(p.out, "%s… and %d more\n", prefix, n.Omitted)
+		return err
+	}
+
+	name := n.Info.Name()
+	if p.Colors != nil {
+		name = p.Colors.paint(name, n)
+	}
+	if p.Highlight[n.Path] {
+		name = "\x1b[7m" + name + "\x1b[0m"
+	}
+	name = p.columns(n.Info) + name
+	if n.LinkTarget != "" {
+		name += " -> " + n.LinkTarget
+	}
+	if p.ShowMIME && n.MIME != "" {
+		name += " [" + n.MIME + "]"
+	}
+	var suffix string
+	switch {
+	case n.Err != nil:
+		suffix = " [" + errorMark(n.Err) + "]"
+	case n.Broken:
+		suffix = " [broken link]"
+	case n.Recursive:
+		suffix = " [recursive, not followed]"
+	case n.LinkTarget != "" && n.Info.Mode()&os.ModeSymlink != 0:
+	case n.Info.IsDir() && p.DirSizes:
+		suffix = " (" + FormatSize(n.Size, p.Units) + ")"
+	case n.Info.IsDir():
+	case n.Info.Size() > 0:
+		suffix = " (" + FormatSize(n.Info.Size(), p.Units) + ")"
+	default:
+		suffix = " (empty)"
+	}
+	if n.Hash != "" {
+		suffix += " " + n.Hash
+	}
+	if p.ShowLines && n.Err == nil {
+		switch {
+		case n.Binary:
+			suffix += " binary"
+		case n.Lines == 1:
+			suffix += " 1 line"
+		case n.Info.IsDir() || n.Info.Mode().IsRegular():
+			suffix += fmt.Sprintf(" %d lines", n.Lines)
+		}
+	}
+	_, err := fmt.Fprintf(p.out, "%s%s%s\n", prefix, name, suffix)
+	return err
+}
+
+// columns returns the enabled metadata columns of info, or "" if none are.
+func (p *TextPrinter) columns(info fs.FileInfo) string {
+	var cols []string
+	if p.ShowInode {
+		id, _ := getFileID(info)
+		cols = append(cols, fmt.Sprintf("%8d", id.ino))
+	}
+	if p.ShowMode {
+		cols = append(cols, info.Mode().String())
+	}
+	uid, gid, ok := getOwner(info)
+	if p.ShowOwner {
+		name := "-"
+		if ok {
+			name = p.names.user(uid)
+		}
+		cols = append(cols, fmt.Sprintf("%-8s", name))
+	}
+	if p.ShowGroup {
+		name := "-"
+		if ok {
+			name = p.names.group(gid)
+		}
+		cols = append(cols, fmt.Sprintf("%-8s", name))
+	}
+	if p.ShowTime {
+		layout := p.TimeFormat
+		if layout == "" {
+			layout = "Jan _2 15:04"
+		}
+		cols = append(cols, info.ModTime().Format(layout))
+	}
+	if len(cols) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(cols, " ") + "]  "
+}
+
+// WantTotals implements Totaler: DirSizes and the byte count of
+// ShowSummary need the size of everything below the directories, also
+// below MaxDepth.
+func (p *TextPrinter) WantTotals() bool {
+	return p.DirSizes || p.ShowSummary
+}
+
+func (p *TextPrinter) Finish(s Stats) error {
+	if p.ShowSummary {
+		total := FormatSize(s.Bytes, p.Units)
+		if p.Units == Bytes {
+			total = fmt.Sprintf("%d bytes", s.Bytes)
+		}
+		if _, err := fmt.Fprintf(p.out, "\n%d directories, %d files, %s\n", s.Dirs, s.Files, total); err != nil {
+			return err
+		}
+	}
+	if p.ShowLines && len(s.Languages) > 0 {
+		if _, err := io.WriteString(p.out, "\n"); err != nil {
+			return err
+		}
+		return WriteLanguages(p.out, s.Languages)
+	}
+	return nil
+}
+
+// errorMark turns "open a/b: permission denied" into "permission denied".
+func errorMark(err error) string {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return pe.Err.Error()
+	}
+	return strings.TrimSpace(err.Error())
+}
@@ -0,0 +1,74 @@
+package tree
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+const testColumnsResult = `├───[drwxr-xr-x -        -        2021-09-08 12:00]  bin
+│	└───[-rwxrwxrwx -        -        2021-09-08 12:00]  run.sh (3b)
+└───[-rw-r--r-- -        -        2021-09-08 12:00]  notes.txt (empty)
+`
+
+func TestTextColumns(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bin":        {Mode: fs.ModeDir | 0755, ModTime: fixtureTime},
+		"bin/run.sh": {Data: []byte("#!\n"), Mode: 0777, ModTime: fixtureTime},
+		"notes.txt":  {Mode: 0644, ModTime: fixtureTime},
+	}
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.ShowMode = true
+	p.ShowOwner = true
+	p.ShowGroup = true
+	p.ShowTime = true
+	p.TimeFormat = "2006-01-02 15:04"
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true}, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testColumnsResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testColumnsResult)
+	}
+}
+
+const testASCIIResult = "|-- a\n" +
+	"|   |-- .hidden (empty)\n" +
+	"|   `-- b\n" +
+	"|       `-- c.txt (3b)\n" +
+	"`-- z.txt (5b)\n"
+
+func TestTextStyle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/c.txt":   {Data: []byte("abc")},
+		"a/.hidden":   {},
+		"z.txt":       {Data: []byte("hello")},
+		".git/config": {},
+	}
+	style, err := NewStyle("ascii", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	p := NewTextPrinter(out)
+	p.Style = style
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true, Exclude: []string{".git"}}, p); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testASCIIResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testASCIIResult)
+	}
+
+	out.Reset()
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true, HideHidden: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "├───a\n│\t└───b\n│\t\t└───c.txt (3b)\n└───z.txt (5b)\n"; out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+
+	if _, err := NewStyle("ebcdic", 0); err == nil {
+		t.Errorf("expected error for unknown charset")
+	}
+}
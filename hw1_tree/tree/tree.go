@@ -0,0 +1,679 @@
+// Package tree walks a directory and reports its entries in the order
+// they are printed by the tree utility.
+package tree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// ErrNotDir is returned when the root passed to Walk is not a directory.
+var ErrNotDir = errors.New("not a directory")
+
+// RootError reports a root path that can't be walked at all.
+type RootError struct {
+	Path string
+	Err  error
+}
+
+func (e *RootError) Error() string {
+	return fmt.Sprintf("tree %s: %v", e.Path, e.Err)
+}
+
+func (e *RootError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every failure met while walking with KeepGoing set.
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d errors, first: %v", len(e), e[0])
+}
+
+// Options control which entries Walk visits.
+type Options struct {
+	PrintFiles bool // visit files, not only directories
+	KeepGoing  bool // don't stop on unreadable directories, see Node.Err
+	MaxDepth   int  // levels to descend, like tree -L; 0 means no limit
+	MaxEntries int  // entries per directory before the rest are summarized; 0 means no limit
+
+	// Include and Exclude are glob patterns with ** support. A pattern
+	// without a slash matches the base name at any level, otherwise the
+	// path relative to the root. Include only applies to files.
+	Include    []string
+	Exclude    []string
+	GitIgnore  bool // hide paths ignored by .gitignore files at any level
+	HideHidden bool // hide entries whose name starts with a dot
+	Prune      bool // hide directories left without files by the filters above
+
+	// The predicates below keep only the entries other than directories
+	// that satisfy all of them and, as with Prune, the directories with
+	// such entries below them. Directories match themselves only when
+	// Types lists ClassDir, and then only the time limits apply to them.
+	MinSize        int64     // 0 means no limit
+	MaxSize        int64     // 0 means no limit
+	ModifiedAfter  time.Time // zero means no limit
+	ModifiedBefore time.Time // zero means no limit
+	Types          []FileClass
+
+	// IncludeMIME and ExcludeMIME are globs matched against the media type
+	// sniffed from the first bytes of files, see Node.MIME. "image" is
+	// short for "image/*". Like the predicates above they prune directories.
+	IncludeMIME []string
+	ExcludeMIME []string
+
+	Symlinks    bool // report symlink targets, see Node.LinkTarget
+	FollowLinks bool // descend into linked directories, implies Symlinks
+
+	// Workers is the number of goroutines reading directories at once.
+	// The output doesn't depend on it; 0 and 1 read sequentially.
+	Workers int
+
+	SortBy    SortKey // "" sorts by name
+	DirsFirst bool    // list directories before files, whatever the sort key
+	Reverse   bool    // reverse the sort order, directories stay first with DirsFirst
+
+	// Hash selects the content hash computed for every regular file when
+	// PrintFiles is set. Files are hashed by Workers goroutines, at least one.
+	Hash HashKind
+
+	// CountLines counts the lines of every regular text file, whether
+	// PrintFiles is set or not, see Node.Lines. Files whose first bytes
+	// don't look like text are not counted.
+	CountLines bool
+
+	SniffMIME bool // report the media type of regular files, see Node.MIME
+}
+
+// Node is a single entry visited by Walk.
+//
+// When a directory has more than Options.MaxEntries entries, the visible ones
+// are followed by a summary node with nil Info and Omitted set to the number
+// of entries left out.
+type Node struct {
+	Path    string // slash-separated, relative to the root
+	Info    fs.FileInfo
+	Depth   int   // 0 for entries directly under the root
+	IsLast  bool  // last entry of its parent directory
+	Err     error // set when the directory or file couldn't be read, with KeepGoing only
+	Omitted int   // number of entries left out, on summary nodes only
+
+	// Size is the file size, or for directories the total size of the
+	// files kept below them. Directory sizes are only computed for visitors
+	// implementing Totaler, with Options.CountLines or when sorting by size,
+	// and then include what is below MaxDepth.
+	Size int64
+
+	// With Options.Symlinks, LinkTarget is the target of a symlink as
+	// stored in the link. With Options.FollowLinks, Info describes the
+	// target rather than the link itself.
+	LinkTarget string
+	Broken     bool // the link target doesn't exist
+	Recursive  bool // the link points to one of its ancestors and wasn't followed
+
+	Hash string // content hash of regular files with Options.Hash, as "algo:hex"
+
+	// With Options.CountLines, Lines is the number of lines of a text file
+	// or the total of the text files kept below a directory.
+	Lines  int64
+	Binary bool // the file isn't text, with Options.CountLines
+
+	// MIME is the media type of a regular file, such as "image/png", as
+	// detected from its content with Options.SniffMIME or a MIME filter.
+	MIME string
+}
+
+// Visitor is called by Walk for every node in print order.
+// A non-nil error stops the walk and is returned by Walk.
+type Visitor interface {
+	Visit(n Node) error
+}
+
+// Stats are the totals of a walk, counting every entry kept by the
+// filters in the directories read, whether it was visited or not.
+type Stats struct {
+	Dirs  int
+	Files int
+
+	// Bytes is the size of the files counted or, when directory totals
+	// are computed, of all the files kept down to the bottom of the tree.
+	Bytes int64
+
+	// With Options.CountLines, the total lines of text files and their
+	// breakdown by language, most lines first.
+	Lines     int64
+	Languages []LanguageStat
+}
+
+// Finisher is implemented by visitors that want the totals of the walk.
+// Finish is called once after the last node has been visited.
+type Finisher interface {
+	Finish(s Stats) error
+}
+
+// Totaler is implemented by visitors that may need the total size of
+// directories, see Node.Size. When WantTotals returns true, Walk reads
+// the whole tree before visiting it rather than visiting it as it is read.
+type Totaler interface {
+	WantTotals() bool
+}
+
+// VisitorFunc adapts an ordinary function to the Visitor interface.
+type VisitorFunc func(n Node) error
+
+func (f VisitorFunc) Visit(n Node) error {
+	return f(n)
+}
+
+// Walk visits the entries under the OS directory root depth-first,
+// sorted by name. Directories are read as they are visited, unless Prune,
+// a predicate or the totals of directories need the whole tree first.
+func Walk(root string, opts Options, v Visitor) error {
+	return WalkContext(context.Background(), root, opts, v)
+}
+
+// WalkContext is Walk that stops reading directories once ctx is done.
+// The entries read until then are still visited, directories that were
+// not read completely with their Node.Err set to the error of ctx, which
+// is also returned.
+func WalkContext(ctx context.Context, root string, opts Options, v Visitor) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return &RootError{Path: root, Err: err}
+	}
+	if !info.IsDir() {
+		return &RootError{Path: root, Err: ErrNotDir}
+	}
+	return walk(ctx, DirFS(root), ".", root, opts, v)
+}
+
+// WalkFS is Walk for the directory root of fsys, such as an embed.FS,
+// an archive opened with OpenArchive or a MemFS.
+func WalkFS(fsys fs.FS, root string, opts Options, v Visitor) error {
+	return WalkFSContext(context.Background(), fsys, root, opts, v)
+}
+
+// WalkFSContext is WalkContext for the directory root of fsys.
+func WalkFSContext(ctx context.Context, fsys fs.FS, root string, opts Options, v Visitor) error {
+	return walk(ctx, fsys, root, root, opts, v)
+}
+
+// walk walks root of fsys, reporting errors about the root under name.
+func walk(ctx context.Context, fsys fs.FS, root, name string, opts Options, v Visitor) error {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return &RootError{Path: name, Err: err}
+	}
+	if !info.IsDir() {
+		return &RootError{Path: name, Err: ErrNotDir}
+	}
+	f, err := newFilter(opts)
+	if err != nil {
+		return err
+	}
+	if err := opts.SortBy.check(); err != nil {
+		return err
+	}
+	if err := opts.Hash.check(); err != nil {
+		return err
+	}
+	if opts.FollowLinks {
+		opts.Symlinks = true
+	}
+	w := &walker{ctx: ctx, fsys: fsys, root: root, opts: opts, filter: f, v: v}
+	w.sniff = len(opts.IncludeMIME) > 0 || len(opts.ExcludeMIME) > 0
+	w.totals = opts.CountLines || opts.SortBy == SortSize
+	if t, ok := v.(Totaler); ok && t.WantTotals() {
+		w.totals = true
+	}
+	if opts.Workers > 1 {
+		w.workers = make(chan struct{}, opts.Workers-1)
+	}
+	var ancestors []fileID
+	if id, ok := getFileID(info); ok {
+		ancestors = append(ancestors, id)
+	}
+
+	// Pruning, the predicates and the totals of directories depend on
+	// what is below them, so the tree is read in full before it is
+	// visited. So is the tree of a file system read in order when files
+	// are hashed, for them all to be read in one pass. Otherwise the tree
+	// is visited as it is read.
+	_, ordered := fsys.(orderedFS)
+	if w.totals || opts.Prune || f.predicates || ordered && opts.Hash != NoHash && opts.PrintFiles {
+		err = w.walkBuffered(name, ancestors)
+	} else {
+		err = w.walkStreamed(name, ancestors)
+	}
+	if err != nil {
+		return err
+	}
+	sortLanguages(w.stats.Languages)
+	if f, ok := v.(Finisher); ok {
+		if err := f.Finish(w.stats); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(w.errs) > 0 {
+		return w.errs
+	}
+	return nil
+}
+
+// walkBuffered reads the whole tree, then visits it.
+func (w *walker) walkBuffered(name string, ancestors []fileID) error {
+	var ignore []ignoreRule
+	if w.opts.GitIgnore {
+		ignore = loadIgnore(w.fsys, w.fsPath(""), "", nil)
+	}
+	items, err := w.listItems("", 0, ignore, ancestors)
+	if err != nil && w.ctx.Err() == nil {
+		return &RootError{Path: name, Err: err}
+	}
+	items, err = w.items(0, items, ignore, ancestors)
+	if err != nil {
+		return err
+	}
+	w.markVisited(items)
+	if err := w.readFiles(w.contentItems(items, nil)); err != nil {
+		return err
+	}
+	if w.opts.CountLines {
+		sumLines(items)
+	}
+	w.tally(items, true)
+	return w.emit("", 0, items)
+}
+
+// item is an entry read by the walker, before it is visited.
+type item struct {
+	path     string
+	info     fs.FileInfo
+	err      error
+	expand   bool // a directory within MaxDepth, whose children are visited
+	read     bool // children were read, also below MaxDepth for the totals
+	visited  bool // the item is visited, not left out by MaxEntries
+	size     int64
+	children []*item
+
+	target    string
+	broken    bool
+	recursive bool
+	hash      string
+	lines     int64
+	binary    bool
+	mime      string
+}
+
+type walker struct {
+	ctx    context.Context
+	fsys   fs.FS
+	root   string // in fsys
+	opts   Options
+	filter *filter
+	v      Visitor
+	errs   Errors
+	stats  Stats
+
+	workers chan struct{} // slots for extra reading goroutines
+	sniff   bool          // detect the media type of files while reading directories
+	totals  bool          // directory sizes and line counts are needed
+}
+
+// readDir returns the items of dir. Once the walk is cancelled it returns
+// those read so far together with the error of the context.
+func (w *walker) readDir(dir string, depth int, ignore []ignoreRule, ancestors []fileID) ([]*item, error) {
+	if w.opts.GitIgnore {
+		ignore = loadIgnore(w.fsys, w.fsPath(dir), dir, ignore)
+	}
+	items, err := w.listItems(dir, depth, ignore, ancestors)
+	if err != nil && w.ctx.Err() == nil {
+		return nil, err
+	}
+	items, ierr := w.items(depth, items, ignore, ancestors)
+	if err == nil {
+		err = ierr
+	}
+	return items, err
+}
+
+// listItems returns the items of dir kept by the filters, in no order.
+func (w *walker) listItems(dir string, depth int, ignore []ignoreRule, ancestors []fileID) ([]*item, error) {
+	var items []*item
+	err := w.readDirFunc(dir, func(info fs.FileInfo) {
+		if it := w.newItem(dir, depth, info, ignore, ancestors); it != nil {
+			items = append(items, it)
+		}
+	})
+	return items, err
+}
+
+// readDirBatch is the number of entries read from a directory at once.
+const readDirBatch = 1024
+
+// readDirFunc calls fn with the info of every entry of dir, not following
+// symlinks where fsys supports them. Entries removed before their info is
+// read are skipped, as happens in trees being written to. Entries are
+// read in batches, each
+// handed to fn before the next is read, checking for cancellation before
+// every batch. Once the walk is cancelled it returns the error of the
+// context.
+func (w *walker) readDirFunc(dir string, fn func(fs.FileInfo)) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	f, err := w.fsys.Open(w.fsPath(dir))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return w.readDirFuncAll(dir, fn)
+	}
+	for {
+		entries, err := d.ReadDir(readDirBatch)
+		for _, e := range entries {
+			info, err := e.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			fn(info)
+		}
+		if err == io.EOF || err == nil && len(entries) == 0 {
+			return nil
+		}
+		if err != nil {
+			return &fs.PathError{Op: "readdir", Path: w.fsPath(dir), Err: err}
+		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// readDirFuncAll is readDirFunc for file systems without fs.ReadDirFile.
+func (w *walker) readDirFuncAll(dir string, fn func(fs.FileInfo)) error {
+	entries, err := fs.ReadDir(w.fsys, w.fsPath(dir))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fn(info)
+	}
+	return nil
+}
+
+// newItem returns the item for the entry info of dir, or nil when the
+// filters hide it. ancestors holds the device/inode of dir and its
+// parents, used to detect symlink loops with FollowLinks.
+func (w *walker) newItem(dir string, depth int, info fs.FileInfo, ignore []ignoreRule, ancestors []fileID) *item {
+	it := &item{path: path.Join(dir, info.Name()), info: info}
+	if info.Mode()&os.ModeSymlink != 0 && w.opts.Symlinks {
+		w.resolveLink(it, ancestors)
+	}
+	if !w.filter.keep(it.path, it.info.IsDir(), ignore) {
+		return nil
+	}
+	if it.info.Mode().IsRegular() && w.sniff {
+		it.mime, it.err = w.sniffMIME(it.path)
+	}
+	if !it.info.IsDir() && it.err == nil && !w.filter.match(it.info, it.mime) {
+		return nil
+	}
+	it.expand = it.info.IsDir() && !it.recursive && (w.opts.MaxDepth == 0 || depth+1 < w.opts.MaxDepth)
+	// Directories below MaxDepth are read for the totals only.
+	it.read = it.expand || w.totals && it.info.IsDir() && !it.recursive
+	if !it.info.IsDir() {
+		it.size = it.info.Size()
+	}
+	return it
+}
+
+// childAncestors returns the ancestors of the entries of the directory it.
+func (w *walker) childAncestors(it *item, ancestors []fileID) []fileID {
+	if id, ok := getFileID(it.info); ok && w.opts.FollowLinks {
+		return append(ancestors[:len(ancestors):len(ancestors)], id)
+	}
+	return ancestors
+}
+
+// items sorts the items of a directory and reads everything below them
+// that can be visited. Visitors are called only after the whole tree is
+// read, so that filtered-out directories can be pruned without breaking
+// IsLast.
+func (w *walker) items(depth int, items []*item, ignore []ignoreRule, ancestors []fileID) ([]*item, error) {
+	if w.opts.SortBy != SortSize {
+		w.sortItems(items)
+	}
+
+	if w.totals || w.opts.MaxEntries == 0 {
+		w.readChildren(items, depth, ignore, ancestors)
+	} else {
+		// Directories are read in runs just long enough to fill MaxEntries
+		// if none of them is pruned, so that nothing is read below the
+		// entries left out.
+		i, shown := 0, 0
+		for i < len(items) && shown < w.opts.MaxEntries {
+			j := i
+			for need := w.opts.MaxEntries - shown; j < len(items) && need > 0; j++ {
+				if w.listed(items[j]) {
+					need--
+				}
+			}
+			w.readChildren(items[i:j], depth, ignore, ancestors)
+			for _, it := range items[i:j] {
+				if w.listed(it) && !w.pruned(it) {
+					shown++
+				}
+			}
+			i = j
+		}
+		for _, it := range items[i:] {
+			it.read = false
+		}
+	}
+
+	kept := items[:0]
+	for _, it := range items {
+		if it.err != nil && !w.opts.KeepGoing && w.ctx.Err() == nil {
+			return nil, it.err
+		}
+		if w.pruned(it) {
+			continue
+		}
+		for _, child := range it.children {
+			it.size += child.size
+		}
+		kept = append(kept, it)
+	}
+	if w.opts.SortBy == SortSize {
+		w.sortItems(kept)
+	}
+	return kept, nil
+}
+
+// readChildren reads the children of the items to be read. Subdirectories
+// are read by spare workers when there are any and by this goroutine
+// otherwise, so a busy pool never blocks the walk.
+func (w *walker) readChildren(items []*item, depth int, ignore []ignoreRule, ancestors []fileID) {
+	wg := &sync.WaitGroup{}
+	for _, it := range items {
+		if !it.read {
+			continue
+		}
+		next := w.childAncestors(it, ancestors)
+		select {
+		case w.workers <- struct{}{}:
+			wg.Add(1)
+			go func(it *item) {
+				defer wg.Done()
+				it.children, it.err = w.readDir(it.path, depth+1, ignore, next)
+				<-w.workers
+			}(it)
+		default:
+			it.children, it.err = w.readDir(it.path, depth+1, ignore, next)
+		}
+	}
+	wg.Wait()
+}
+
+// listed reports whether it counts toward MaxEntries: directories always
+// do, files only with PrintFiles.
+func (w *walker) listed(it *item) bool {
+	return w.opts.PrintFiles || it.info.IsDir()
+}
+
+// pruned reports whether it is a directory the filters left empty.
+func (w *walker) pruned(it *item) bool {
+	return it.expand && it.read && it.err == nil && len(it.children) == 0 && (w.opts.Prune || !w.filter.match(it.info, ""))
+}
+
+// shown returns the items of a directory that are visited and the number
+// of those left out by MaxEntries.
+func (w *walker) shown(items []*item) ([]*item, int) {
+	if !w.opts.PrintFiles {
+		var dirs []*item
+		for _, it := range items {
+			if it.info.IsDir() {
+				dirs = append(dirs, it)
+			}
+		}
+		items = dirs
+	}
+	if w.opts.MaxEntries > 0 && len(items) > w.opts.MaxEntries {
+		return items[:w.opts.MaxEntries], len(items) - w.opts.MaxEntries
+	}
+	return items, 0
+}
+
+// markVisited sets visited on the items that emit will visit.
+func (w *walker) markVisited(items []*item) {
+	shown, _ := w.shown(items)
+	for _, it := range shown {
+		it.visited = true
+		if it.expand {
+			w.markVisited(it.children)
+		}
+	}
+}
+
+// tally collects the totals and, in print order, the errors of items.
+// Entries below MaxDepth, read for the totals, add to the bytes and lines
+// but are not counted, as they are never visited.
+func (w *walker) tally(items []*item, count bool) {
+	for _, it := range items {
+		if it.err != nil && it.err != w.ctx.Err() {
+			w.errs = append(w.errs, it.err)
+		}
+		if it.info.IsDir() {
+			if count {
+				w.stats.Dirs++
+			}
+		} else {
+			if count {
+				w.stats.Files++
+			}
+			w.stats.Bytes += it.size
+			if w.opts.CountLines && it.info.Mode().IsRegular() && !it.binary && it.err == nil {
+				w.stats.Lines += it.lines
+				w.stats.Languages = addLanguage(w.stats.Languages, it.info.Name(), it.lines)
+			}
+		}
+		w.tally(it.children, count && it.expand)
+	}
+}
+
+// resolveLink fills in the link target of it. With FollowLinks it also
+// replaces the link info with the info of the target, unless the target
+// is a directory among ancestors.
+func (w *walker) resolveLink(it *item, ancestors []fileID) {
+	p := w.fsPath(it.path)
+	if lfs, ok := w.fsys.(ReadLinkFS); ok {
+		it.target, _ = lfs.ReadLink(p)
+	}
+	target, err := fs.Stat(w.fsys, p)
+	if err != nil {
+		it.broken = true
+		return
+	}
+	if !w.opts.FollowLinks {
+		return
+	}
+	if id, ok := getFileID(target); ok && target.IsDir() {
+		for _, a := range ancestors {
+			if a == id {
+				it.recursive = true
+				return
+			}
+		}
+	}
+	it.info = target
+}
+
+func (w *walker) fsPath(p string) string {
+	return path.Join(w.root, p)
+}
+
+// node returns the node visited for it.
+func (w *walker) node(it *item, depth int, last bool) Node {
+	return Node{
+		Path:   it.path,
+		Info:   it.info,
+		Depth:  depth,
+		IsLast: last,
+		Err:    it.err,
+		Size:   it.size,
+
+		LinkTarget: it.target,
+		Broken:     it.broken,
+		Recursive:  it.recursive,
+
+		Hash:   it.hash,
+		Lines:  it.lines,
+		Binary: it.binary,
+		MIME:   it.mime,
+	}
+}
+
+func (w *walker) emit(dir string, depth int, items []*item) error {
+	items, omitted := w.shown(items)
+	for i, it := range items {
+		if err := w.v.Visit(w.node(it, depth, i == len(items)-1 && omitted == 0)); err != nil {
+			return err
+		}
+		if it.expand {
+			if err := w.emit(it.path, depth+1, it.children); err != nil {
+				return err
+			}
+		}
+	}
+	if omitted > 0 {
+		return w.v.Visit(Node{Path: dir, Depth: depth, IsLast: true, Omitted: omitted})
+	}
+	return nil
+}
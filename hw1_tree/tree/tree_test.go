@@ -0,0 +1,451 @@
+package tree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWalkBadRoot(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := Walk("../testdata/nonexistent", Options{PrintFiles: true}, NewTextPrinter(out))
+	var rootErr *RootError
+	if !errors.As(err, &rootErr) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected RootError wrapping ErrNotExist, got %v", err)
+	}
+
+	err = Walk("../testdata/zzfile.txt", Options{PrintFiles: true}, NewTextPrinter(out))
+	if !errors.Is(err, ErrNotDir) {
+		t.Errorf("expected ErrNotDir, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output for bad root, got %q", out.String())
+	}
+}
+
+func TestWalkKeepGoing(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b/c", "d"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	locked := filepath.Join(root, "b")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	out := new(bytes.Buffer)
+	if err := Walk(root, Options{}, NewTextPrinter(out)); err == nil {
+		t.Errorf("expected error for unreadable directory")
+	}
+
+	out.Reset()
+	err := Walk(root, Options{KeepGoing: true}, NewTextPrinter(out))
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs[0], os.ErrPermission) {
+		t.Errorf("expected one permission error, got %v", err)
+	}
+	expected := "├───a\n├───b [permission denied]\n└───d\n"
+	if out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+}
+
+// vanishFS is a MapFS whose entry gone is removed between the listing of
+// its directory and the reading of its info.
+type vanishFS struct {
+	fstest.MapFS
+	gone string
+}
+
+func (v vanishFS) Open(name string) (fs.File, error) {
+	f, err := v.MapFS.Open(name)
+	if d, ok := f.(fs.ReadDirFile); ok && err == nil {
+		return vanishDir{d, name, v.gone}, nil
+	}
+	return f, err
+}
+
+type vanishDir struct {
+	fs.ReadDirFile
+	name, gone string
+}
+
+func (d vanishDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries, err := d.ReadDirFile.ReadDir(n)
+	for i, e := range entries {
+		if path.Join(d.name, e.Name()) == d.gone {
+			entries[i] = vanishEntry{e}
+		}
+	}
+	return entries, err
+}
+
+type vanishEntry struct {
+	fs.DirEntry
+}
+
+func (e vanishEntry) Info() (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "lstat", Path: e.Name(), Err: fs.ErrNotExist}
+}
+
+func TestWalkVanished(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/x": {},
+		"a/y": {},
+		"a/z": {},
+	}
+	out := new(bytes.Buffer)
+	if err := WalkFS(vanishFS{fsys, "a/y"}, ".", Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "└───a\n\t├───x (empty)\n\t└───z (empty)\n"
+	if out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+}
+
+func TestWalkVisitor(t *testing.T) {
+	var got []string
+	v := VisitorFunc(func(n Node) error {
+		got = append(got, fmt.Sprintf("%d %v %s", n.Depth, n.IsLast, n.Path))
+		return nil
+	})
+	if err := Walk("../testdata/zline", Options{PrintFiles: true}, v); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"0 false empty.txt",
+		"0 true lorem",
+		"1 false lorem/dolor.txt",
+		"1 false lorem/gopher.png",
+		"1 true lorem/ipsum",
+		"2 true lorem/ipsum/gopher.png",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", got, expected)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := Walk("../testdata", Options{}, VisitorFunc(func(n Node) error {
+		calls++
+		return stop
+	}))
+	if err != stop || calls != 1 {
+		t.Errorf("expected walk to stop after first visit, got %v after %d calls", err, calls)
+	}
+}
+
+const testLimitsResult = `├───project
+│	├───file.txt (19b)
+│	└───gopher.png (70372b)
+├───static
+│	├───a_lorem
+│	├───css
+│	└───… and 4 more
+└───… and 2 more
+`
+
+func TestWalkLimits(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := Walk("../testdata", Options{PrintFiles: true, MaxDepth: 2, MaxEntries: 2}, NewTextPrinter(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testLimitsResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testLimitsResult)
+	}
+
+	e, err := Collect("../testdata", Options{MaxDepth: 1, MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Children) != 1 || e.Omitted != 2 || len(e.Children[0].Children) != 0 {
+		t.Errorf("unexpected collected tree: %+v", e)
+	}
+}
+
+const testSymlinksResult = `├───a
+│	├───b
+│	│	├───lf -> ../f (3b)
+│	│	└───up -> .. [recursive, not followed]
+│	├───broken -> nope [broken link]
+│	└───f (3b)
+└───la -> a
+	├───b
+	│	├───lf -> ../f (3b)
+	│	└───up -> .. [recursive, not followed]
+	├───broken -> nope [broken link]
+	└───f (3b)
+`
+
+func TestWalkSymlinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a/f"), []byte("hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{"a/b/up": "..", "a/b/lf": "../f", "a/broken": "nope", "la": "a"}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skip("symlinks not supported:", err)
+		}
+	}
+
+	out := new(bytes.Buffer)
+	if err := Walk(root, Options{PrintFiles: true, FollowLinks: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testSymlinksResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testSymlinksResult)
+	}
+
+	out.Reset()
+	if err := Walk(root, Options{PrintFiles: true}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "└───la (1b)\n") {
+		t.Errorf("expected symlink printed as a file without Symlinks, got\n%v", out.String())
+	}
+}
+
+func TestWalkWorkers(t *testing.T) {
+	expected := new(bytes.Buffer)
+	p := NewTextPrinter(expected)
+	p.DirSizes = true
+	p.ShowSummary = true
+	if err := Walk("../testdata", Options{PrintFiles: true}, p); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		out := new(bytes.Buffer)
+		p := NewTextPrinter(out)
+		p.DirSizes = true
+		p.ShowSummary = true
+		if err := Walk("../testdata", Options{PrintFiles: true, Workers: 4}, p); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expected.String() {
+			t.Fatalf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected.String())
+		}
+	}
+}
+
+// cancelFS cancels a walk when the directory at path is opened.
+type cancelFS struct {
+	fstest.MapFS
+	path   string
+	cancel context.CancelFunc
+}
+
+func (c cancelFS) Open(name string) (fs.File, error) {
+	if name == c.path {
+		c.cancel()
+	}
+	return c.MapFS.Open(name)
+}
+
+const testWalkContextResult = `├───a
+│	└───x (empty)
+├───b [context canceled]
+│	└───y (empty)
+└───c [context canceled]
+`
+
+func TestWalkContext(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/x": {},
+		"b/y": {},
+		"c/z": {},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	out := new(bytes.Buffer)
+	err := WalkFSContext(ctx, cancelFS{fsys, "b", cancel}, ".", Options{PrintFiles: true}, NewTextPrinter(out))
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if out.String() != testWalkContextResult {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), testWalkContextResult)
+	}
+
+	out.Reset()
+	err = WalkContext(ctx, "../testdata", Options{PrintFiles: true}, NewTextPrinter(out))
+	if err != context.Canceled || out.Len() != 0 {
+		t.Errorf("expected nothing for a cancelled walk, got %v and %q", err, out.String())
+	}
+
+	e, err := CollectFSContext(ctx, fsys, ".", Options{})
+	if err != context.Canceled || e == nil || len(e.Children) != 0 {
+		t.Errorf("expected an empty tree for a cancelled walk, got %v and %+v", err, e)
+	}
+}
+
+func TestWalkBatches(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < readDirBatch*2+1; i++ {
+		fsys[fmt.Sprintf("big/%05d", i)] = &fstest.MapFile{}
+	}
+	e, err := CollectFS(fsys, ".", Options{PrintFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(e.Children[0].Children); n != readDirBatch*2+1 {
+		t.Errorf("expected %d entries, got %d", readDirBatch*2+1, n)
+	}
+}
+
+// genFS is a directory of n empty files made up as they are listed, so
+// that nothing holds on to their info but the walker. Before every batch
+// it calls before with the number of entries listed so far.
+type genFS struct {
+	n      int
+	before func(listed int)
+	freed  *int32 // entries whose info has been garbage collected
+}
+
+func (g genFS) Open(name string) (fs.File, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &genDir{genFS: g}, nil
+}
+
+type genDir struct {
+	genFS
+	listed int
+}
+
+func (d *genDir) Stat() (fs.FileInfo, error) { return &genInfo{name: ".", dir: true}, nil }
+func (d *genDir) Read([]byte) (int, error)   { return 0, errors.New("is a directory") }
+func (d *genDir) Close() error               { return nil }
+
+func (d *genDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	d.before(d.listed)
+	if d.listed == d.n {
+		return nil, io.EOF
+	}
+	var entries []fs.DirEntry
+	for ; d.listed < d.n && len(entries) < n; d.listed++ {
+		info := &genInfo{name: fmt.Sprintf("%05d", d.listed)}
+		runtime.SetFinalizer(info, func(*genInfo) { atomic.AddInt32(d.freed, 1) })
+		entries = append(entries, info)
+	}
+	return entries, nil
+}
+
+// genInfo is both the fs.DirEntry and the fs.FileInfo of an entry.
+type genInfo struct {
+	name string
+	dir  bool
+}
+
+func (i *genInfo) Name() string               { return i.name }
+func (i *genInfo) IsDir() bool                { return i.dir }
+func (i *genInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *genInfo) Info() (fs.FileInfo, error) { return i, nil }
+func (i *genInfo) Size() int64                { return 0 }
+func (i *genInfo) ModTime() time.Time         { return time.Time{} }
+func (i *genInfo) Sys() interface{}           { return nil }
+
+func (i *genInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func TestWalkBatchesFreed(t *testing.T) {
+	// With MaxEntries, each batch is filtered before the next one is read
+	// and the entries left out are dropped, so the first batches can be
+	// freed before the directory is read to the end.
+	var freed int32
+	n := readDirBatch * 3
+	fsys := genFS{n: n, freed: &freed}
+	fsys.before = func(listed int) {
+		if listed != n {
+			return
+		}
+		for i := 0; i < 100 && atomic.LoadInt32(&freed) < readDirBatch; i++ {
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		if f := atomic.LoadInt32(&freed); f < readDirBatch {
+			t.Errorf("expected the first batch to be freed, only %d of %d entries were", f, n)
+		}
+	}
+	out := new(bytes.Buffer)
+	if err := WalkFS(fsys, ".", Options{PrintFiles: true, MaxEntries: 1}, NewTextPrinter(out)); err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("├───00000 (empty)\n└───… and %d more\n", n-1)
+	if out.String() != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", out.String(), expected)
+	}
+}
+
+// logFS records in log the directories opened.
+type logFS struct {
+	fstest.MapFS
+	log *[]string
+}
+
+func (l logFS) Open(name string) (fs.File, error) {
+	*l.log = append(*l.log, "open "+name)
+	return l.MapFS.Open(name)
+}
+
+func TestWalkStreamed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/x": {},
+		"b/y": {},
+		"c/z": {},
+	}
+	var log []string
+	v := VisitorFunc(func(n Node) error {
+		if n.Info == nil {
+			log = append(log, fmt.Sprintf("omitted %d", n.Omitted))
+			return nil
+		}
+		log = append(log, "visit "+n.Path)
+		return nil
+	})
+	if err := WalkFS(logFS{fsys, &log}, ".", Options{}, v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "open . open a visit a open b visit b open c visit c"
+	if got := strings.Join(log, " "); got != expected {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", got, expected)
+	}
+
+	// Buffered with Prune or not, nothing is read below the entries left out.
+	expected = "open . open a visit a omitted 2"
+	for _, opts := range []Options{{MaxEntries: 1}, {MaxEntries: 1, Prune: true}} {
+		log = nil
+		if err := WalkFS(logFS{fsys, &log}, ".", opts, v); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(log, " "); got != expected {
+			t.Errorf("directories left out were read with %+v\nGot:\n%v\nExpected:\n%v", opts, got, expected)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// WatchOptions control how Watch notices changes.
+type WatchOptions struct {
+	Poll     bool          // poll even where change notifications are available
+	Interval time.Duration // between polls, 1s if zero
+	Debounce time.Duration // quiet time after a notification before redrawing, 200ms if zero
+}
+
+// notifier reports changes in watched directories.
+type notifier interface {
+	Add(dir string) error
+	Events() <-chan struct{}
+	Close() error
+}
+
+var errNoNotify = errors.New("change notifications are not supported")
+
+// Watch calls draw once, then again every time the tree under the OS
+// directory root changes, until ctx is done. It uses inotify on Linux and
+// polls elsewhere. draw gets the paths, relative to root, of entries that
+// were created or changed in size or mode since the last call. The tree
+// is always walked with KeepGoing, as directories come and go while it is
+// read.
+func Watch(ctx context.Context, root string, opts Options, wopts WatchOptions, draw func(changed map[string]bool) error) error {
+	opts.KeepGoing = true
+	if wopts.Interval <= 0 {
+		wopts.Interval = time.Second
+	}
+	if wopts.Debounce <= 0 {
+		wopts.Debounce = 200 * time.Millisecond
+	}
+	prev, err := watchCollect(root, opts)
+	if err != nil {
+		return err
+	}
+	if err := draw(nil); err != nil {
+		return err
+	}
+
+	var n notifier
+	if !wopts.Poll {
+		if n, err = newNotifier(); err == nil {
+			defer n.Close()
+			watchDirs(n, root, prev)
+		} else {
+			n = nil
+		}
+	}
+
+	for {
+		if !waitChange(ctx, n, wopts) {
+			return nil
+		}
+		cur, err := watchCollect(root, opts)
+		if err != nil {
+			return err
+		}
+		d := Diff(prev, cur, DiffOptions{IgnoreTime: true})
+		if !d.HasChanges() {
+			continue
+		}
+		prev = cur
+		if n != nil {
+			watchDirs(n, root, cur)
+		}
+		if err := draw(changedPaths(d, "", map[string]bool{})); err != nil {
+			return err
+		}
+	}
+}
+
+// watchCollect is Collect that tolerates unreadable directories, which
+// come and go in a tree under construction.
+func watchCollect(root string, opts Options) (*Entry, error) {
+	e, err := Collect(root, opts)
+	if e == nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// waitChange blocks until n reports a change followed by a quiet period,
+// or until the next poll without a notifier. It returns false when ctx
+// is done.
+func waitChange(ctx context.Context, n notifier, wopts WatchOptions) bool {
+	if n == nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wopts.Interval):
+			return true
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-n.Events():
+	}
+	quiet := time.NewTimer(wopts.Debounce)
+	defer quiet.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-n.Events():
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+			quiet.Reset(wopts.Debounce)
+		case <-quiet.C:
+			return true
+		}
+	}
+}
+
+// watchDirs adds every directory of e to n. Adding a directory again is
+// harmless, and removed directories drop out by themselves.
+func watchDirs(n notifier, root string, e *Entry) {
+	var add func(e *Entry, p string)
+	add = func(e *Entry, p string) {
+		if e.Type != "dir" {
+			return
+		}
+		n.Add(filepath.Join(root, filepath.FromSlash(p)))
+		for _, c := range e.Children {
+			add(c, path.Join(p, c.Name))
+		}
+	}
+	add(e, ".")
+}
+
+// changedPaths returns the paths of added and changed entries of d.
+func changedPaths(d *DiffEntry, p string, paths map[string]bool) map[string]bool {
+	for _, c := range d.Children {
+		if !c.changes {
+			continue
+		}
+		cp := path.Join(p, c.Name)
+		if c.Status == Added || c.Status == Changed {
+			paths[cp] = true
+		}
+		changedPaths(c, cp, paths)
+	}
+	return paths
+}
@@ -0,0 +1,55 @@
+package tree
+
+import (
+	"os"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+type inotify struct {
+	f      *os.File
+	events chan struct{}
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	// A non-blocking descriptor goes through the runtime poller, so Close
+	// interrupts a pending Read.
+	in := &inotify{f: os.NewFile(uintptr(fd), "inotify"), events: make(chan struct{}, 1)}
+	go in.read()
+	return in, nil
+}
+
+func (in *inotify) Add(dir string) error {
+	fd := int(in.f.Fd())
+	_, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+	return os.NewSyscallError("inotify_add_watch", err)
+}
+
+func (in *inotify) Events() <-chan struct{} {
+	return in.events
+}
+
+func (in *inotify) Close() error {
+	return in.f.Close()
+}
+
+// read turns every batch of inotify events into a single pending signal.
+// Watch rereads the tree anyway, so the events themselves don't matter.
+func (in *inotify) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := in.f.Read(buf); err != nil {
+			return
+		}
+		select {
+		case in.events <- struct{}{}:
+		default:
+		}
+	}
+}
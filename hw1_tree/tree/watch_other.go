@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package tree
+
+func newNotifier() (notifier, error) {
+	return nil, errNoNotify
+}
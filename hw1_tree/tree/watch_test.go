@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		draws := make(chan map[string]bool)
+		done := make(chan error)
+		wopts := WatchOptions{Poll: poll, Interval: 20 * time.Millisecond, Debounce: 20 * time.Millisecond}
+		go func() {
+			done <- Watch(ctx, dir, Options{PrintFiles: true}, wopts, func(changed map[string]bool) error {
+				select {
+				case draws <- changed:
+				case <-ctx.Done():
+				}
+				return nil
+			})
+		}()
+
+		steps := []struct {
+			change   func() error
+			expected map[string]bool
+		}{
+			{nil, nil},
+			{func() error { return os.Mkdir(filepath.Join(dir, "sub"), 0755) }, map[string]bool{"sub": true}},
+			{func() error { return os.WriteFile(filepath.Join(dir, "sub", "b.txt"), nil, 0644) }, map[string]bool{"sub/b.txt": true}},
+			{func() error { return os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abc"), 0644) }, map[string]bool{"a.txt": true}},
+		}
+		for _, step := range steps {
+			if step.change != nil {
+				if err := step.change(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			select {
+			case changed := <-draws:
+				if !reflect.DeepEqual(changed, step.expected) {
+					t.Errorf("poll %v: changed %v, expected %v", poll, changed, step.expected)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("poll %v: no redraw, expected %v", poll, step.expected)
+			}
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("poll %v: %v", poll, err)
+		}
+	}
+}
+
+func TestWatchUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	// An unreadable directory stands for one vanishing while it is read:
+	// Watch keeps going rather than return.
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+	ctx, cancel := context.WithCancel(context.Background())
+	draws := 0
+	err := Watch(ctx, dir, Options{}, WatchOptions{Poll: true, Interval: time.Millisecond}, func(map[string]bool) error {
+		draws++
+		cancel()
+		return nil
+	})
+	if err != nil || draws != 1 {
+		t.Errorf("expected one draw and no error, got %d and %v", draws, err)
+	}
+}